@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// LoadIdempotencyTTL reads IDEMPOTENCY_KEY_TTL (e.g. "24h"), the time an
+// Idempotency-Key is remembered after its first use.
+func LoadIdempotencyTTL() time.Duration {
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		if ttl, err := time.ParseDuration(v); err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+	return 24 * time.Hour
+}
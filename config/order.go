@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// CancellationPolicy controls when customers may cancel their own orders.
+type CancellationPolicy struct {
+	// Window is how long after creation an order can still be cancelled; zero means no limit.
+	Window time.Duration
+	// Statuses lists the order statuses from which a customer may cancel.
+	Statuses []string
+}
+
+// LoadCancellationPolicy reads ORDER_CANCEL_WINDOW (e.g. "24h") and
+// ORDER_CANCELLABLE_STATUSES (comma separated), falling back to sensible defaults.
+func LoadCancellationPolicy() CancellationPolicy {
+	policy := CancellationPolicy{
+		Window: 24 * time.Hour,
+		// "paid" only covers orders stored while payment still set it as their status
+		Statuses: []string{"pending", "paid", "approved"},
+	}
+
+	if v := os.Getenv("ORDER_CANCEL_WINDOW"); v != "" {
+		if window, err := time.ParseDuration(v); err == nil {
+			policy.Window = window
+		}
+	}
+
+	if v := os.Getenv("ORDER_CANCELLABLE_STATUSES"); v != "" {
+		policy.Statuses = nil
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.ToLower(strings.TrimSpace(status)); status != "" {
+				policy.Statuses = append(policy.Statuses, status)
+			}
+		}
+	}
+
+	return policy
+}
+
+// OrderExpiryPolicy controls the background job that cancels orders left unpaid.
+type OrderExpiryPolicy struct {
+	// After is how long an order may stay unpaid; zero disables expiry.
+	After time.Duration
+	// Interval is how often the job looks for expired orders.
+	Interval time.Duration
+}
+
+// LoadOrderExpiryPolicy reads ORDER_UNPAID_EXPIRY (e.g. "48h", "0" to disable) and
+// ORDER_EXPIRY_INTERVAL (e.g. "5m").
+func LoadOrderExpiryPolicy() OrderExpiryPolicy {
+	policy := OrderExpiryPolicy{
+		After:    48 * time.Hour,
+		Interval: 5 * time.Minute,
+	}
+
+	if v := os.Getenv("ORDER_UNPAID_EXPIRY"); v != "" {
+		if after, err := time.ParseDuration(v); err == nil && after >= 0 {
+			policy.After = after
+		}
+	}
+
+	if v := os.Getenv("ORDER_EXPIRY_INTERVAL"); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil && interval > 0 {
+			policy.Interval = interval
+		}
+	}
+
+	return policy
+}
+
+// LoadReturnWindow reads RETURN_WINDOW (e.g. "336h"), how long after delivery an
+// order can still be returned. Zero means no limit; the default is 14 days.
+func LoadReturnWindow() time.Duration {
+	window := 14 * 24 * time.Hour
+
+	if v := os.Getenv("RETURN_WINDOW"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed >= 0 {
+			window = parsed
+		}
+	}
+
+	return window
+}
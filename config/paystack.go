@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// PaystackConfig holds what the Paystack client needs to reach the API.
+type PaystackConfig struct {
+	// BaseURL is the API root; tests point it at a local fake.
+	BaseURL   string
+	SecretKey string
+	// CallbackURL is where Paystack sends the customer after paying; empty uses the dashboard setting.
+	CallbackURL string
+	// Timeout bounds every call to Paystack.
+	Timeout time.Duration
+}
+
+// LoadPaystackConfig reads PAYSTACK_BASE_URL, PAYSTACK_SECRET_KEY, PAYSTACK_CALLBACK_URL
+// and PAYSTACK_TIMEOUT (e.g. "15s").
+func LoadPaystackConfig() PaystackConfig {
+	cfg := PaystackConfig{
+		BaseURL:     "https://api.paystack.co",
+		SecretKey:   os.Getenv("PAYSTACK_SECRET_KEY"),
+		CallbackURL: os.Getenv("PAYSTACK_CALLBACK_URL"),
+		Timeout:     15 * time.Second,
+	}
+
+	if v := os.Getenv("PAYSTACK_BASE_URL"); v != "" {
+		cfg.BaseURL = strings.TrimRight(v, "/")
+	}
+
+	if v := os.Getenv("PAYSTACK_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout > 0 {
+			cfg.Timeout = timeout
+		}
+	}
+
+	return cfg
+}
+
+// ReconciliationPolicy controls the job that re-verifies card payments left pending.
+type ReconciliationPolicy struct {
+	// After is how long a payment may stay pending before it is re-verified; zero disables the job.
+	After time.Duration
+	// Interval is how often the job runs.
+	Interval time.Duration
+	// HoldTimeout is how long the wallet part of a split payment stays held while its card
+	// part is pending; the job releases it afterwards. Zero keeps holds until the card part settles.
+	HoldTimeout time.Duration
+}
+
+// LoadReconciliationPolicy reads PAYMENT_RECONCILE_AFTER (e.g. "30m", "0" to disable)
+// PAYMENT_RECONCILE_INTERVAL (e.g. "15m") and PAYMENT_WALLET_HOLD_TIMEOUT (e.g. "1h").
+func LoadReconciliationPolicy() ReconciliationPolicy {
+	policy := ReconciliationPolicy{
+		After:       30 * time.Minute,
+		Interval:    15 * time.Minute,
+		HoldTimeout: time.Hour,
+	}
+
+	if v := os.Getenv("PAYMENT_RECONCILE_AFTER"); v != "" {
+		if after, err := time.ParseDuration(v); err == nil && after >= 0 {
+			policy.After = after
+		}
+	}
+
+	if v := os.Getenv("PAYMENT_RECONCILE_INTERVAL"); v != "" {
+		if interval, err := time.ParseDuration(v); err == nil && interval > 0 {
+			policy.Interval = interval
+		}
+	}
+
+	if v := os.Getenv("PAYMENT_WALLET_HOLD_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil && timeout >= 0 {
+			policy.HoldTimeout = timeout
+		}
+	}
+
+	return policy
+}
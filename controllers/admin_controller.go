@@ -4,9 +4,11 @@ import (
 	"adhomes-backend/models"
 	"adhomes-backend/services"
 	"adhomes-backend/utils"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -75,10 +77,23 @@ func (ac *AdminController) AddProduct(c *gin.Context) {
 		return
 	}
 
-	var price float64
+	// Prices are entered in naira, e.g. "1500.50"
+	price, err := models.ParseMoney(priceStr, models.DefaultCurrency)
+	if err != nil || price.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be a positive amount with at most two decimals"})
+		return
+	}
+
+	prices, err := parsePriceOverrides(c.PostFormArray("prices"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var weight float64
 	var stock int
-	fmt.Sscanf(priceStr, "%f", &price)
 	fmt.Sscanf(stockStr, "%d", &stock)
+	fmt.Sscanf(c.PostForm("weight_kg"), "%f", &weight)
 
 	var imageURL, imageID string
 
@@ -98,7 +113,9 @@ func (ac *AdminController) AddProduct(c *gin.Context) {
 		Description: description,
 		Category:    category,
 		Price:       price,
+		Prices:      prices,
 		Stock:       stock,
+		WeightKg:    weight,
 		ImageURL:    imageURL,
 		ImageID:     imageID,
 	}
@@ -115,6 +132,28 @@ func (ac *AdminController) AddProduct(c *gin.Context) {
 	})
 }
 
+// parsePriceOverrides reads per-currency prices given as repeated "prices" form
+// fields such as "USD:12.99".
+func parsePriceOverrides(values []string) ([]models.Money, error) {
+	prices := []models.Money{}
+	for _, value := range values {
+		currency, amount, ok := strings.Cut(value, ":")
+		if !ok || len(strings.TrimSpace(currency)) != 3 {
+			return nil, errors.New("prices must look like USD:12.99")
+		}
+
+		price, err := models.ParseMoney(amount, currency)
+		if err != nil || price.Amount <= 0 {
+			return nil, errors.New("invalid price for " + strings.ToUpper(currency))
+		}
+		if price.Currency == models.DefaultCurrency {
+			return nil, errors.New("use price for the base currency")
+		}
+		prices = append(prices, price)
+	}
+	return prices, nil
+}
+
 func (ac *AdminController) UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
 	update := make(map[string]interface{})
@@ -123,15 +162,31 @@ func (ac *AdminController) UpdateProduct(c *gin.Context) {
 		update["name"] = v
 	}
 	if v := c.PostForm("price"); v != "" {
-		var price float64
-		fmt.Sscanf(v, "%f", &price)
+		price, err := models.ParseMoney(v, models.DefaultCurrency)
+		if err != nil || price.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "price must be a positive amount with at most two decimals"})
+			return
+		}
 		update["price"] = price
 	}
+	if values := c.PostFormArray("prices"); len(values) > 0 {
+		prices, err := parsePriceOverrides(values)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update["prices"] = prices
+	}
 	if v := c.PostForm("stock"); v != "" {
 		var stock int
 		fmt.Sscanf(v, "%d", &stock)
 		update["stock"] = stock
 	}
+	if v := c.PostForm("weight_kg"); v != "" {
+		var weight float64
+		fmt.Sscanf(v, "%f", &weight)
+		update["weight_kg"] = weight
+	}
 
 	file, err := c.FormFile("image")
 	if err == nil {
@@ -204,21 +259,38 @@ func (ac *AdminController) GetProductByID(ctx *gin.Context) {
 
 // === Order Management ===
 func (ac *AdminController) GetAllOrders(ctx *gin.Context) {
-	orders, err := ac.orderServices.GetAllOrders()
+	var query models.OrderListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	orders, pagination, err := ac.orderServices.GetAllOrders(query)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		switch err.Error() {
+		case "invalid from date", "invalid to date", "invalid sort key":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		}
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"orders": orders,
+		"orders":     orders,
+		"pagination": pagination,
 	})
 }
 
 func (ac *AdminController) ApproveOrder(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	if err := ac.orderServices.ApproveOrder(id); err != nil {
+	if err := ac.orderServices.ApproveOrder(id, ctx.GetString("user_id")); err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve order"})
 		return
 	}
@@ -230,7 +302,22 @@ func (ac *AdminController) ApproveOrder(ctx *gin.Context) {
 func (ac *AdminController) CancelOrder(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	if err := ac.orderServices.CancelOrder(id); err != nil {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = ctx.ShouldBindJSON(&body)
+
+	if err := ac.orderServices.CancelOrder(id, ctx.GetString("user_id"), body.Reason); err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		// The order is cancelled either way; only the card refund needs another go
+		if strings.HasPrefix(err.Error(), "order was cancelled but") {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
 		return
 	}
@@ -239,6 +326,40 @@ func (ac *AdminController) CancelOrder(ctx *gin.Context) {
 	})
 }
 
+// PUT /admin/orders/:id/status  {"status": "processing" | "preparing" | "out for delivery" | "delivered"}
+func (ac *AdminController) UpdateOrderStatus(ctx *gin.Context) {
+	var body struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		return
+	}
+
+	if err := ac.orderServices.UpdateFulfilmentStatus(ctx.Param("id"), ctx.GetString("user_id"), body.Status); err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		switch err.Error() {
+		case "invalid fulfilment status", "Invalid order id":
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "order status was changed by another request":
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Order status updated successfully",
+		"status":  utils.NormalizeStatus(body.Status),
+	})
+}
+
 // === User Management ===
 func (ac *AdminController) GetAllUsers(ctx *gin.Context) {
 	users, err := ac.userServices.GetAllUsers()
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CheckoutController struct {
+	checkoutService services.CheckoutService
+}
+
+func NewCheckoutController(checkoutService services.CheckoutService) *CheckoutController {
+	return &CheckoutController{
+		checkoutService: checkoutService,
+	}
+}
+
+// POST /checkout/guest
+func (cc *CheckoutController) GuestCheckout(c *gin.Context) {
+	var order models.Order
+	if err := c.ShouldBindJSON(&order); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	checkout, err := cc.checkoutService.GuestCheckout(order)
+	if err != nil {
+		if respondProviderError(c, err) {
+			return
+		}
+
+		switch {
+		case err.Error() == "customer name, email and phone are required",
+			err.Error() == "invalid customer email":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case isOrderRejection(err):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Order created successfully",
+		"order":          checkout.Order,
+		"tracking_token": checkout.TrackingToken,
+		"payment":        checkout.Payment,
+		"payment_url":    checkout.PaymentURL,
+	})
+}
+
+// GET /track/:token
+func (cc *CheckoutController) TrackOrder(c *gin.Context) {
+	tracking, err := cc.checkoutService.TrackOrder(c.Param("token"))
+	if err != nil {
+		if err.Error() == "Order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load order"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CouponController struct {
+	couponService services.CouponService
+}
+
+func NewCouponController(couponService services.CouponService) *CouponController {
+	return &CouponController{
+		couponService: couponService,
+	}
+}
+
+// POST /admin/coupons
+func (cc *CouponController) CreateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := cc.couponService.CreateCoupon(coupon)
+	if err != nil {
+		if err.Error() == "coupon code already exists" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "coupon created",
+		"coupon":  created,
+	})
+}
+
+// GET /admin/coupons
+func (cc *CouponController) GetCoupons(c *gin.Context) {
+	coupons, err := cc.couponService.GetCoupons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coupons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupons": coupons,
+	})
+}
+
+// GET /admin/coupons/:id
+func (cc *CouponController) GetCouponByID(c *gin.Context) {
+	coupon, err := cc.couponService.GetCouponByID(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid coupon id":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "coupon not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coupon"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupon": coupon,
+	})
+}
+
+// PUT /admin/coupons/:id
+func (cc *CouponController) UpdateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := cc.couponService.UpdateCoupon(c.Param("id"), coupon)
+	if err != nil {
+		switch err.Error() {
+		case "coupon not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "coupon code already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "coupon updated",
+		"coupon":  updated,
+	})
+}
+
+// DELETE /admin/coupons/:id
+func (cc *CouponController) DeleteCoupon(c *gin.Context) {
+	if err := cc.couponService.DeleteCoupon(c.Param("id")); err != nil {
+		if err.Error() == "coupon not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "coupon deleted"})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeliveryZoneController struct {
+	zoneService services.DeliveryZoneService
+}
+
+func NewDeliveryZoneController(zoneService services.DeliveryZoneService) *DeliveryZoneController {
+	return &DeliveryZoneController{
+		zoneService: zoneService,
+	}
+}
+
+// POST /admin/delivery-zones
+func (zc *DeliveryZoneController) CreateZone(c *gin.Context) {
+	var zone models.DeliveryZone
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := zc.zoneService.CreateZone(zone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "delivery zone created",
+		"zone":    created,
+	})
+}
+
+// GET /admin/delivery-zones
+func (zc *DeliveryZoneController) GetZones(c *gin.Context) {
+	zones, err := zc.zoneService.GetZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve delivery zones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"zones": zones,
+	})
+}
+
+// PUT /admin/delivery-zones/:id
+func (zc *DeliveryZoneController) UpdateZone(c *gin.Context) {
+	id := c.Param("id")
+
+	var zone models.DeliveryZone
+	if err := c.ShouldBindJSON(&zone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := zc.zoneService.UpdateZone(id, zone)
+	if err != nil {
+		if err.Error() == "delivery zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "delivery zone updated",
+		"zone":    updated,
+	})
+}
+
+// DELETE /admin/delivery-zones/:id
+func (zc *DeliveryZoneController) DeleteZone(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := zc.zoneService.DeleteZone(id); err != nil {
+		if err.Error() == "delivery zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "delivery zone deleted"})
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExchangeRateController struct {
+	rateService services.ExchangeRateService
+}
+
+func NewExchangeRateController(rateService services.ExchangeRateService) *ExchangeRateController {
+	return &ExchangeRateController{
+		rateService: rateService,
+	}
+}
+
+// POST /admin/exchange-rates
+func (ec *ExchangeRateController) CreateRate(c *gin.Context) {
+	var rate models.ExchangeRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := ec.rateService.CreateRate(rate)
+	if err != nil {
+		switch err.Error() {
+		case "exchange rate for this currency already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "the base currency does not need an exchange rate", "rate must be greater than zero":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "exchange rate created",
+		"exchange_rate": created,
+	})
+}
+
+// GET /admin/exchange-rates
+func (ec *ExchangeRateController) GetRates(c *gin.Context) {
+	rates, err := ec.rateService.GetRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency":  models.DefaultCurrency,
+		"exchange_rates": rates,
+	})
+}
+
+// PUT /admin/exchange-rates/:id
+func (ec *ExchangeRateController) UpdateRate(c *gin.Context) {
+	var rate models.ExchangeRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := ec.rateService.UpdateRate(c.Param("id"), rate)
+	if err != nil {
+		switch err.Error() {
+		case "exchange rate not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "exchange rate for this currency already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "exchange rate updated",
+		"exchange_rate": updated,
+	})
+}
+
+// DELETE /admin/exchange-rates/:id
+func (ec *ExchangeRateController) DeleteRate(c *gin.Context) {
+	if err := ec.rateService.DeleteRate(c.Param("id")); err != nil {
+		if err.Error() == "exchange rate not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "exchange rate deleted"})
+}
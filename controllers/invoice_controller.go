@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceController struct {
+	invoiceService services.InvoiceService
+}
+
+func NewInvoiceController(invoiceService services.InvoiceService) *InvoiceController {
+	return &InvoiceController{
+		invoiceService: invoiceService,
+	}
+}
+
+// GET /user/orders/:id/invoice
+func (ic *InvoiceController) GetUserInvoice(c *gin.Context) {
+	pdf, number, err := ic.invoiceService.GetUserInvoice(c.Param("id"), c.GetString("user_id"))
+	ic.respond(c, pdf, number, err)
+}
+
+// GET /admin/orders/:id/invoice
+func (ic *InvoiceController) GetInvoice(c *gin.Context) {
+	pdf, number, err := ic.invoiceService.GetInvoice(c.Param("id"))
+	ic.respond(c, pdf, number, err)
+}
+
+func (ic *InvoiceController) respond(c *gin.Context, pdf []byte, number string, err error) {
+	if err != nil {
+		switch err.Error() {
+		case "Invalid order id":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invoice"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+number+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
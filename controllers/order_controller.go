@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"adhomes-backend/models"
 	"adhomes-backend/services"
+	"adhomes-backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,8 +32,15 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	// Orders are always owned by the authenticated user
+	order.UserID = c.GetString("user_id")
+
 	createdOrder, err := oc.orderService.CreateOrder(order)
 	if err != nil {
+		if isOrderRejection(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -42,13 +51,67 @@ func (oc *OrderController) CreateOrder(c *gin.Context) {
 	})
 }
 
+// isOrderRejection reports whether placing an order failed because of what was
+// ordered, rather than a fault on our side.
+func isOrderRejection(err error) bool {
+	switch err.Error() {
+	case "invalid delivery type",
+		"unsupported currency",
+		"we do not deliver to this address",
+		"invalid coupon code",
+		"coupon is not active",
+		"coupon is not yet valid",
+		"coupon has expired",
+		"order does not meet the coupon's minimum value",
+		"coupon does not apply to any item in this order",
+		"coupon usage limit reached",
+		"you have already used this coupon":
+		return true
+	}
+	return false
+}
+
+// -----------------------------
+// Claim Guest Order
+// -----------------------------
+func (oc *OrderController) ClaimGuestOrder(c *gin.Context) {
+	var body struct {
+		TrackingToken string `json:"tracking_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tracking token is required"})
+		return
+	}
+
+	order, err := oc.orderService.ClaimGuestOrder(body.TrackingToken, c.GetString("user_id"))
+	if err != nil {
+		switch err.Error() {
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "order was placed with a different email":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "order has already been claimed":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order added to your account",
+		"order":   order,
+	})
+}
+
 // -----------------------------
 // Get Order By ID
 // -----------------------------
 func (oc *OrderController) GetOrderByID(c *gin.Context) {
 	id := c.Param("id")
 
-	order, err := oc.orderService.GetOrderByID(id)
+	order, err := oc.orderService.GetOrderByID(id, c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -60,10 +123,10 @@ func (oc *OrderController) GetOrderByID(c *gin.Context) {
 }
 
 // -----------------------------
-// Get Orders By User ID
+// Get Orders Of The Authenticated User
 // -----------------------------
 func (oc *OrderController) GetOrdersByUserID(c *gin.Context) {
-	userID := c.Param("user_id")
+	userID := c.GetString("user_id")
 
 	orders, err := oc.orderService.GetOrdersByUserID(userID)
 	if err != nil {
@@ -82,10 +145,23 @@ func (oc *OrderController) GetOrdersByUserID(c *gin.Context) {
 func (oc *OrderController) DeleteOrder(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := oc.orderService.DeleteOrder(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+	if err := oc.orderService.DeleteOrder(id, c.GetString("user_id")); err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch err.Error() {
+		case "Invalid order id":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "only pending, unpaid orders can be deleted", "order status was changed by another request":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -108,8 +184,16 @@ func (oc *OrderController) UpdateOrder(c *gin.Context) {
 		return
 	}
 
-	updatedOrder, err := oc.orderService.UpdateOrder(id, input)
+	updatedOrder, err := oc.orderService.UpdateOrder(id, c.GetString("user_id"), input)
 	if err != nil {
+		switch err.Error() {
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case "only pending, unpaid orders can be updated":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -139,14 +223,26 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	if err := oc.orderService.UpdateOrderStatus(id, body.Status); err != nil {
+	if err := oc.orderService.UpdateOrderStatus(id, c.GetString("user_id"), body.Status); err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
 		switch err.Error() {
-		case "invalid order ID":
+		case "Invalid order id":
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		case "invalid order status":
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case "order not found":
+		case "customers can only cancel orders":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "Order not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "order cannot be cancelled in its current status",
+			"cancellation window has passed",
+			"card payments can only be cancelled by support":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -158,3 +254,43 @@ func (oc *OrderController) UpdateOrderStatus(c *gin.Context) {
 		"status":  body.Status,
 	})
 }
+
+// -----------------------------
+// Cancel Order (customer)
+// -----------------------------
+func (oc *OrderController) CancelOrder(c *gin.Context) {
+	id := c.Param("id")
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	order, err := oc.orderService.CancelMyOrder(id, c.GetString("user_id"), body.Reason)
+	if err != nil {
+		var transitionErr *utils.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch err.Error() {
+		case "Invalid order id":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "order cannot be cancelled in its current status",
+			"cancellation window has passed",
+			"card payments can only be cancelled by support":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order cancelled successfully",
+		"order":   order,
+	})
+}
@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"adhomes-backend/models"
 	"adhomes-backend/services"
@@ -27,9 +30,26 @@ func (pc *PaymentController) MakePayment(c *gin.Context) {
 		return
 	}
 
+	// The paying user always comes from the token, never from the body
+	req.UserID = c.GetString("user_id")
+
 	payment, paymentURL, err := pc.paymentService.MakePayment(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if respondProviderError(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "order has already been paid",
+			"order has been cancelled",
+			"order can no longer be paid",
+			"a payment for this order is already in progress",
+			"order status was changed by another request":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -43,3 +63,151 @@ func (pc *PaymentController) MakePayment(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, response)
 }
+
+// GET /user/payments/:reference/verify
+func (pc *PaymentController) VerifyPayment(c *gin.Context) {
+	payment, err := pc.paymentService.VerifyUserPayment(c.Param("reference"), c.GetString("user_id"))
+	if err != nil {
+		if respondProviderError(c, err) {
+			return
+		}
+		if err.Error() == "payment not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment": payment})
+}
+
+// GET /user/payments
+func (pc *PaymentController) GetUserPayments(c *gin.Context) {
+	var query models.PaymentListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	payments, pagination, err := pc.paymentService.GetUserPayments(c.GetString("user_id"), query)
+	if err != nil {
+		respondPaymentListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payments":   payments,
+		"pagination": pagination,
+	})
+}
+
+// GET /user/orders/:id/payments
+func (pc *PaymentController) GetOrderPayments(c *gin.Context) {
+	payments, err := pc.paymentService.GetOrderPayments(c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		switch err.Error() {
+		case "Invalid order id":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payments"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payments": payments})
+}
+
+// GET /admin/payments
+func (pc *PaymentController) GetAllPayments(c *gin.Context) {
+	var query models.PaymentListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	payments, pagination, totals, err := pc.paymentService.GetAllPayments(query)
+	if err != nil {
+		respondPaymentListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payments":   payments,
+		"pagination": pagination,
+		"totals":     totals,
+	})
+}
+
+func respondPaymentListError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid from date", "invalid to date":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve payments"})
+	}
+}
+
+// POST /admin/payments/:id/refund
+func (pc *PaymentController) RefundPayment(c *gin.Context) {
+	var req models.RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := pc.paymentService.RefundPayment(c.Param("id"), req, c.GetString("user_id"))
+	if err != nil {
+		if respondProviderError(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "payment not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "Invalid payment id",
+			err.Error() == "refund amount must be positive",
+			err.Error() == "refund currency does not match payment currency":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "refunds cannot be refunded",
+			err.Error() == "wallet top-ups cannot be refunded",
+			err.Error() == "only successful payments can be refunded",
+			err.Error() == "payment has already been fully refunded",
+			strings.HasPrefix(err.Error(), "refund exceeds"):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refund payment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"refund": refund})
+}
+
+// GET /admin/payments/reconciliation
+func (pc *PaymentController) GetReconciliationReports(c *gin.Context) {
+	reports, err := pc.paymentService.GetReconciliationReports()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reconciliation reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// respondProviderError answers with 502, or 504 on a timeout, when err came from the
+// payment provider, and reports whether it did.
+func respondProviderError(c *gin.Context, err error) bool {
+	var providerErr *services.ProviderError
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+
+	status := http.StatusBadGateway
+	if providerErr.Timeout {
+		status = http.StatusGatewayTimeout
+	}
+	c.JSON(status, gin.H{"error": "Payment provider unavailable, please try again"})
+	return true
+}
@@ -113,8 +113,20 @@ func (pc *ProductController) DeleteProduct(c *gin.Context) {
 // GET ALL PRODUCTS (PUBLIC)
 // --------------------
 func (pc *ProductController) GetAllProducts(c *gin.Context) {
-	products, err := pc.productService.GetAllProducts()
+	var products []models.Product
+	var err error
+
+	// ?currency=USD prices the listing in another currency
+	if currency := c.Query("currency"); currency != "" {
+		products, err = pc.productService.GetAllProductsIn(currency)
+	} else {
+		products, err = pc.productService.GetAllProducts()
+	}
 	if err != nil {
+		if err.Error() == "unsupported currency" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch products",
 		})
@@ -132,17 +144,28 @@ func (pc *ProductController) GetAllProducts(c *gin.Context) {
 func (pc *ProductController) GetProductByID(c *gin.Context) {
 	id := c.Param("id")
 
-	product, err := pc.productService.GetProductByID(id)
+	var product *models.Product
+	var err error
+	if currency := c.Query("currency"); currency != "" {
+		product, err = pc.productService.GetProductByIDIn(id, currency)
+	} else {
+		product, err = pc.productService.GetProductByID(id)
+	}
 	if err != nil {
-		if err.Error() == "product not found" {
+		switch err.Error() {
+		case "product not found":
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": err.Error(),
 			})
-			return
+		case "unsupported currency", "invalid product id":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch product",
+			})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch product",
-		})
 		return
 	}
 
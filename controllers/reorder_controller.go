@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReorderController struct {
+	reorderService services.ReorderService
+}
+
+func NewReorderController(reorderService services.ReorderService) *ReorderController {
+	return &ReorderController{
+		reorderService: reorderService,
+	}
+}
+
+// POST /user/orders/:id/reorder  {"mode": "order" | "cart"}
+func (rc *ReorderController) Reorder(c *gin.Context) {
+	var body struct {
+		Mode string `json:"mode"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	result, err := rc.reorderService.Reorder(c.Param("id"), c.GetString("user_id"), body.Mode)
+	if err != nil {
+		switch {
+		case err.Error() == "Invalid order id", err.Error() == "invalid reorder mode":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "none of the items on this order are available",
+			strings.HasPrefix(err.Error(), "insufficient stock"):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case isOrderRejection(err):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
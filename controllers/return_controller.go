@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+	"adhomes-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReturnPhotos caps how many photos a customer can attach to one return.
+const maxReturnPhotos = 5
+
+type ReturnController struct {
+	returnService services.ReturnService
+}
+
+func NewReturnController(returnService services.ReturnService) *ReturnController {
+	return &ReturnController{
+		returnService: returnService,
+	}
+}
+
+// POST /user/orders/:id/returns (multipart: items, reason, refund_method, photos)
+// items is a JSON array such as [{"product_id": "...", "quantity": 1}].
+func (rc *ReturnController) CreateReturn(c *gin.Context) {
+	var items []models.ReturnItem
+	if err := json.Unmarshal([]byte(c.PostForm("items")), &items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "items must be a JSON array of product_id and quantity"})
+		return
+	}
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["photos"]
+	}
+	if len(files) > maxReturnPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many photos"})
+		return
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "photos must be images"})
+			return
+		}
+	}
+
+	photos := make([]models.ReturnPhoto, 0, len(files))
+	for _, file := range files {
+		url, publicID, err := utils.UploadToCloudinaryFolder(file, "adhomes/returns")
+		if err != nil {
+			deleteReturnPhotos(photos)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "photo upload failed"})
+			return
+		}
+		photos = append(photos, models.ReturnPhoto{URL: url, PublicID: publicID})
+	}
+
+	created, err := rc.returnService.CreateReturn(models.Return{
+		OrderID:      c.Param("id"),
+		UserID:       c.GetString("user_id"),
+		Items:        items,
+		Reason:       c.PostForm("reason"),
+		Photos:       photos,
+		RefundMethod: c.PostForm("refund_method"),
+	})
+	if err != nil {
+		deleteReturnPhotos(photos)
+
+		switch err.Error() {
+		case "Invalid order id",
+			"a reason is required",
+			"invalid refund method",
+			"a return must contain at least one item",
+			"item quantity must be greater than zero":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "Order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "only delivered orders can be returned",
+			"return window has passed",
+			"order has no payment to refund",
+			"orders paid in another currency cannot be refunded to the wallet",
+			"item is not part of this order",
+			"cannot return more items than were ordered":
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Return requested successfully",
+		"return":  created,
+	})
+}
+
+// deleteReturnPhotos removes photos uploaded for a return that was not saved.
+func deleteReturnPhotos(photos []models.ReturnPhoto) {
+	for _, photo := range photos {
+		_ = utils.DeleteImageFromCloudinary(photo.PublicID)
+	}
+}
+
+// GET /user/returns
+func (rc *ReturnController) GetUserReturns(c *gin.Context) {
+	returns, err := rc.returnService.GetUserReturns(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve returns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"returns": returns})
+}
+
+// GET /user/returns/:id
+func (rc *ReturnController) GetUserReturn(c *gin.Context) {
+	ret, err := rc.returnService.GetUserReturn(c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// GET /admin/returns?status=requested
+func (rc *ReturnController) GetReturns(c *gin.Context) {
+	returns, err := rc.returnService.GetReturns(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve returns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"returns": returns})
+}
+
+// GET /admin/returns/:id
+func (rc *ReturnController) GetReturn(c *gin.Context) {
+	ret, err := rc.returnService.GetReturn(c.Param("id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// PUT /admin/returns/:id/approve
+func (rc *ReturnController) ApproveReturn(c *gin.Context) {
+	var body struct {
+		Note string `json:"note"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	ret, err := rc.returnService.ApproveReturn(c.Param("id"), c.GetString("user_id"), body.Note)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return approved",
+		"return":  ret,
+	})
+}
+
+// PUT /admin/returns/:id/reject
+func (rc *ReturnController) RejectReturn(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+
+	ret, err := rc.returnService.RejectReturn(c.Param("id"), c.GetString("user_id"), body.Reason)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return rejected",
+		"return":  ret,
+	})
+}
+
+// PUT /admin/returns/:id/receive
+func (rc *ReturnController) ReceiveReturn(c *gin.Context) {
+	ret, err := rc.returnService.ReceiveReturn(c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return received and refunded",
+		"return":  ret,
+	})
+}
+
+func respondReturnError(c *gin.Context, err error) {
+	// The return is received either way; only the card refund needs another go
+	if strings.HasPrefix(err.Error(), "return was received but") {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "Invalid return id", "a reason is required to reject a return":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "Return not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "return has already been processed",
+		"only approved returns can be received":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "order has no payment to refund",
+		"order has less left to refund than the return is worth",
+		"wallet not found for this currency":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxController struct {
+	taxService services.TaxService
+}
+
+func NewTaxController(taxService services.TaxService) *TaxController {
+	return &TaxController{
+		taxService: taxService,
+	}
+}
+
+// POST /admin/tax-rates
+func (tc *TaxController) CreateTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := tc.taxService.CreateTaxRate(rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "tax rate created",
+		"tax_rate": created,
+	})
+}
+
+// GET /admin/tax-rates
+func (tc *TaxController) GetTaxRates(c *gin.Context) {
+	rates, err := tc.taxService.GetTaxRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tax rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tax_rates": rates,
+	})
+}
+
+// PUT /admin/tax-rates/:id
+func (tc *TaxController) UpdateTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := tc.taxService.UpdateTaxRate(c.Param("id"), rate)
+	if err != nil {
+		if err.Error() == "tax rate not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "tax rate updated",
+		"tax_rate": updated,
+	})
+}
+
+// DELETE /admin/tax-rates/:id
+func (tc *TaxController) DeleteTaxRate(c *gin.Context) {
+	if err := tc.taxService.DeleteTaxRate(c.Param("id")); err != nil {
+		if err.Error() == "tax rate not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tax rate deleted"})
+}
+
+// GET /admin/reports/tax?from=2025-01-01&to=2025-01-31
+func (tc *TaxController) TaxReport(c *gin.Context) {
+	rows, err := tc.taxService.TaxReport(c.Query("from"), c.Query("to"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid from date", "invalid to date":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build tax report"})
+		}
+		return
+	}
+
+	// Totals are kept per currency
+	totalTax := map[string]models.Money{}
+	netSales := map[string]models.Money{}
+	for _, row := range rows {
+		totalTax[row.TaxAmount.Currency] = totalTax[row.TaxAmount.Currency].Add(row.TaxAmount)
+		netSales[row.NetSales.Currency] = netSales[row.NetSales.Currency].Add(row.NetSales)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rows":      rows,
+		"net_sales": netSales,
+		"total_tax": totalTax,
+	})
+}
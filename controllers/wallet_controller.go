@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WalletController struct {
+	walletService  services.WalletService
+	paymentService services.PaymentService
+}
+
+func NewWalletController(walletService services.WalletService, paymentService services.PaymentService) *WalletController {
+	return &WalletController{
+		walletService:  walletService,
+		paymentService: paymentService,
+	}
+}
+
+// GET /user/wallet
+func (wc *WalletController) GetWallet(c *gin.Context) {
+	wallet, err := wc.walletService.GetWalletByUserID(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve wallet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wallet": wallet})
+}
+
+// POST /user/wallet/topup  {"amount": {"amount": 500000, "currency": "NGN"}}
+func (wc *WalletController) TopUp(c *gin.Context) {
+	var req models.TopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, paymentURL, err := wc.paymentService.StartWalletTopUp(c.GetString("user_id"), req.Amount)
+	if err != nil {
+		if respondProviderError(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "top-up amount must be greater than zero", "top-up currency does not match wallet currency":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start top-up"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"payment":     payment,
+		"payment_url": paymentURL,
+	})
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+
+	"adhomes-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody bounds how much of a webhook request is read.
+const maxWebhookBody = 1 << 20
+
+type WebhookController struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookController(webhookService services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// POST /webhooks/paystack
+// The signature covers the raw body, so it is read as-is rather than bound.
+func (wc *WebhookController) HandlePaystack(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody)
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := wc.webhookService.HandlePaystack(body, c.GetHeader("x-paystack-signature")); err != nil {
+		switch err.Error() {
+		case "invalid webhook signature":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case "invalid webhook payload", "invalid webhook amount":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			// Paystack retries until it gets a 2xx
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "event received"})
+}
+
+// GET /admin/webhooks?status=failed
+func (wc *WebhookController) GetEvents(c *gin.Context) {
+	events, err := wc.webhookService.GetEvents(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// POST /admin/webhooks/:id/replay
+func (wc *WebhookController) ReplayEvent(c *gin.Context) {
+	event, err := wc.webhookService.ReplayEvent(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid webhook event id", "invalid webhook payload", "invalid webhook amount":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "webhook event not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "event replayed",
+		"event":   event,
+	})
+}
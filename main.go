@@ -2,9 +2,18 @@ package main
 
 import (
 	"adhomes-backend/config"
+	"adhomes-backend/repositories"
 	"adhomes-backend/routes"
 	"adhomes-backend/utils"
+	"adhomes-backend/workers"
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -17,6 +26,11 @@ func main() {
 	// Connect to MongoDB
 	config.ConnectDB()
 
+	// Convert amounts still stored as float naira into kobo
+	if err := repositories.MigrateMoney(config.DB); err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize Cloudinary
 	if err := utils.InitCloudinary(); err != nil {
 		log.Fatal(err)
@@ -25,9 +39,29 @@ func main() {
 	// Create Gin router
 	router := gin.Default()
 
-	// Setup all routes
-	routes.SetupRoutes(router)
+	// Setup all routes and background jobs
+	scheduler := workers.NewScheduler(repositories.NewLeaseRepository(config.DB.Collection("leases")))
+	routes.SetupRoutes(router, scheduler)
+	scheduler.Start()
 
 	// Start server
-	router.Run(":8080")
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for Ctrl+C or SIGTERM, then let in-flight requests and jobs finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("server shutdown:", err)
+	}
+	scheduler.Stop()
 }
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyHeader   = "Idempotency-Key"
+	maxIdempotencyKey   = 255
+	idempotencyReplayed = "Idempotent-Replayed"
+
+	// idempotencyLease is how long a request may hold its key before a retry can take
+	// it over, for requests that die before storing their response.
+	idempotencyLease = time.Minute
+)
+
+// Idempotency makes a route safe to retry. When a request carries an Idempotency-Key
+// header, the first response for that key and user is stored and replayed for
+// later requests with the same key and body. A different body under the same key
+// is rejected with 422, and a retry that arrives while the first request is still
+// running gets 409, until its lease runs out and the retry takes the key over.
+// Server errors are not stored, so those requests can be retried.
+// Requests without the header are passed through unchanged. Must run after AuthMiddleware.
+func Idempotency(repo *repositories.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		hash := requestHash(c.Request.Method, c.Request.URL.Path, body)
+		now := time.Now()
+		record, reserved, err := repo.Reserve(ctx, models.IdempotencyKey{
+			UserID:      c.GetString("user_id"),
+			Key:         key,
+			RequestHash: hash,
+			LockedUntil: now.Add(idempotencyLease),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != hash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case record.Status != models.IdempotencyCompleted:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header(idempotencyReplayed, "true")
+				c.Data(record.ResponseCode, record.ResponseContentType, record.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The handler has finished; use a fresh context so a slow handler cannot starve the write.
+		saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer saveCancel()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			err = repo.Release(saveCtx, record.ID)
+		} else {
+			err = repo.Complete(saveCtx, record.ID, recorder.Status(), recorder.body.Bytes(), recorder.Header().Get("Content-Type"))
+		}
+		if err != nil {
+			log.Println("failed to save idempotency key:", err)
+		}
+	}
+}
+
+// requestHash fingerprints a request so a reused key with a different request can be detected.
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies everything written to the client so it can be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"adhomes-backend/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRequestHashDistinguishesRequests(t *testing.T) {
+	base := requestHash("POST", "/user/orders", []byte(`{"items":[]}`))
+
+	assert.Equal(t, base, requestHash("POST", "/user/orders", []byte(`{"items":[]}`)))
+	assert.NotEqual(t, base, requestHash("POST", "/user/orders", []byte(`{"items":[1]}`)))
+	assert.NotEqual(t, base, requestHash("POST", "/user/payments", []byte(`{"items":[]}`)))
+}
+
+func TestIdempotencyLeasesTheKeyAndTakesOverLapsedLeases(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("mock", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),                                     // delete of a lapsed reservation
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),                                     // insert
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}), // complete
+		)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/user/orders", func(c *gin.Context) { c.Set("user_id", "ada@example.com") },
+			Idempotency(repositories.NewIdempotencyRepository(mt.Coll), 24*time.Hour),
+			func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"ok": true}) })
+
+		req := httptest.NewRequest(http.MethodPost, "/user/orders", strings.NewReader(`{"items":[]}`))
+		req.Header.Set(IdempotencyHeader, "order-1")
+		w := httptest.NewRecorder()
+		before := time.Now()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var commands []event.CommandStartedEvent
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			commands = append(commands, *e)
+		}
+		require.Len(t, commands, 3)
+
+		deleteFilter := commands[0].Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q").Document()
+		clauses, err := deleteFilter.Lookup("$or").Array().Values()
+		require.NoError(t, err)
+		require.Len(t, clauses, 2)
+		lapsed := clauses[1].Document()
+		assert.Equal(t, "in_progress", lapsed.Lookup("status").StringValue())
+		assert.NotEmpty(t, lapsed.Lookup("locked_until", "$lte").Time())
+
+		inserted := commands[1].Command.Lookup("documents").Array().Index(0).Value().Document()
+		lockedUntil := inserted.Lookup("locked_until").Time()
+		assert.WithinDuration(t, before.Add(idempotencyLease), lockedUntil, 5*time.Second)
+		assert.True(t, lockedUntil.Before(inserted.Lookup("expires_at").Time()))
+	})
+}
@@ -0,0 +1,10 @@
+package models
+
+// GuestCheckout is the result of a guest checkout: the order, the token the guest
+// tracks it with, and the payment they complete it with.
+type GuestCheckout struct {
+	Order         Order   `json:"order"`
+	TrackingToken string  `json:"tracking_token"`
+	Payment       Payment `json:"payment"`
+	PaymentURL    string  `json:"payment_url"`
+}
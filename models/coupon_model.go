@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CouponPercentage = "percentage"
+	CouponFixed      = "fixed"
+)
+
+type Coupon struct {
+	ID   primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Code string             `json:"code" bson:"code" binding:"required"`
+
+	// Type is percentage (Value is a percent of the eligible lines) or fixed (Amount comes off).
+	Type          string  `json:"type" bson:"type" binding:"required,oneof=percentage fixed"`
+	Value         float64 `json:"value,omitempty" bson:"value,omitempty"`
+	Amount        Money   `json:"amount" bson:"amount"`
+	MinOrderValue Money   `json:"min_order_value" bson:"min_order_value"`
+
+	StartsAt  *time.Time `json:"starts_at,omitempty" bson:"starts_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+
+	// Zero limits mean unlimited.
+	UsageLimit   int `json:"usage_limit" bson:"usage_limit"`
+	PerUserLimit int `json:"per_user_limit" bson:"per_user_limit"`
+	UsedCount    int `json:"used_count" bson:"used_count"`
+
+	// When set, only lines in these categories or for these products are discounted.
+	Categories []string `json:"categories" bson:"categories"`
+	ProductIDs []string `json:"product_ids" bson:"product_ids"`
+
+	Active    bool      `json:"active" bson:"active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CouponUsage counts how many times one user has redeemed a coupon.
+type CouponUsage struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CouponID primitive.ObjectID `json:"coupon_id" bson:"coupon_id"`
+	UserID   string             `json:"user_id" bson:"user_id"`
+	Count    int                `json:"count" bson:"count"`
+}
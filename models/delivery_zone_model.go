@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeliveryFeeFlat     = "flat"
+	DeliveryFeeWeight   = "weight"
+	DeliveryFeeFreeOver = "free_over"
+)
+
+// DeliveryZone is an area we deliver to and the rule used to price delivery there.
+// Empty location fields match any value, so a zone with only Country set covers the whole country.
+type DeliveryZone struct {
+	ID   primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name string             `json:"name" bson:"name" binding:"required"`
+
+	Country      string `json:"country" bson:"country"`
+	State        string `json:"state" bson:"state"`
+	City         string `json:"city" bson:"city"`
+	PostalPrefix string `json:"postal_prefix" bson:"postal_prefix"`
+
+	// FeeType is one of flat, weight or free_over.
+	FeeType string `json:"fee_type" bson:"fee_type" binding:"required,oneof=flat weight free_over"`
+	// FlatFee is charged by flat zones, and by free_over zones below the threshold.
+	FlatFee Money `json:"flat_fee" bson:"flat_fee"`
+	// BaseFee plus PerKgFee for every started kilogram is charged by weight zones.
+	BaseFee  Money `json:"base_fee" bson:"base_fee"`
+	PerKgFee Money `json:"per_kg_fee" bson:"per_kg_fee"`
+	// FreeOverAmount is the order subtotal from which free_over zones deliver for free.
+	FreeOverAmount Money `json:"free_over_amount" bson:"free_over_amount"`
+
+	Active    bool      `json:"active" bson:"active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
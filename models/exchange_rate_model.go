@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExchangeRate lets prices kept in DefaultCurrency be shown and charged in Currency.
+// Rate is the number of DefaultCurrency units one unit of Currency costs, e.g. 1550 NGN per USD.
+type ExchangeRate struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Currency  string             `json:"currency" bson:"currency" binding:"required,len=3"`
+	Rate      float64            `json:"rate" bson:"rate" binding:"required,gt=0"`
+	Active    bool               `json:"active" bson:"active"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyKey remembers the first response to a request sent with an
+// Idempotency-Key header so that retries can be answered without re-running it.
+type IdempotencyKey struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	UserID      string             `bson:"user_id"`
+	Key         string             `bson:"key"`
+	RequestHash string             `bson:"request_hash"`
+	Status      string             `bson:"status"`
+
+	// LockedUntil is when an in-progress reservation lapses. A request that died
+	// before storing its response leaves the key to the first retry after that.
+	LockedUntil time.Time `bson:"locked_until"`
+
+	ResponseCode        int    `bson:"response_code,omitempty"`
+	ResponseBody        []byte `bson:"response_body,omitempty"`
+	ResponseContentType string `bson:"response_content_type,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
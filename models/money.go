@@ -0,0 +1,206 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts that do not name a currency.
+const DefaultCurrency = "NGN"
+
+// Money is an amount in the minor unit of its currency (kobo for NGN).
+// Every currency we handle has two decimal places.
+//
+// Rounding: amounts only become fractional when a percentage or a rate is
+// applied (discounts, VAT, per-kg fees). Those results are rounded once, to the
+// nearest minor unit with halves away from zero. Sums and differences of
+// rounded amounts are exact.
+type Money struct {
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// NewMoney returns amount minor units of currency, defaulting to DefaultCurrency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: normalizeCurrency(currency)}
+}
+
+// MoneyFromMajor converts an amount in major units (naira) to Money, rounding to the nearest minor unit.
+func MoneyFromMajor(amount float64, currency string) Money {
+	return NewMoney(roundHalfAway(amount*100), currency)
+}
+
+// ParseMoney reads a decimal amount in major units such as "1500" or "1500.50"
+// without going through floating point.
+func ParseMoney(value string, currency string) (Money, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if value == "" {
+		return Money{}, errors.New("amount is required")
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > 2 {
+		return Money{}, errors.New("amount has more than two decimal places")
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, errors.New("invalid amount")
+	}
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, errors.New("invalid amount")
+	}
+	minor, _ := strconv.ParseInt(frac, 10, 64)
+
+	amount := major*100 + minor
+	if negative {
+		amount = -amount
+	}
+	return NewMoney(amount, currency), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+func roundHalfAway(x float64) int64 {
+	return int64(math.Round(x))
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.currencyWith(other)}
+}
+
+// Sub returns m - other. Both must be in the same currency.
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.currencyWith(other)}
+}
+
+// Mul returns m multiplied by a whole quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// Share returns the part/whole fraction of m, rounded.
+func (m Money) Share(part, whole int64) Money {
+	if whole == 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: roundHalfAway(float64(m.Amount) * float64(part) / float64(whole)), Currency: m.Currency}
+}
+
+// Percent returns rate percent of m, rounded.
+func (m Money) Percent(rate float64) Money {
+	return Money{Amount: roundHalfAway(float64(m.Amount) * rate / 100), Currency: m.Currency}
+}
+
+// IncludedTax returns the part of m, a tax-inclusive amount, that is tax at rate percent, rounded.
+func (m Money) IncludedTax(rate float64) Money {
+	return Money{Amount: roundHalfAway(float64(m.Amount) * rate / (100 + rate)), Currency: m.Currency}
+}
+
+// Convert expresses m in currency at rate, the number of m's units one unit of currency costs.
+func (m Money) Convert(currency string, rate float64) Money {
+	return NewMoney(roundHalfAway(float64(m.Amount)/rate), currency)
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Min returns the smaller of m and other.
+func (m Money) Min(other Money) Money {
+	if other.Amount < m.Amount {
+		return Money{Amount: other.Amount, Currency: m.currencyWith(other)}
+	}
+	return m
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Cmp compares m with other, returning -1, 0 or 1.
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Major returns the amount in major units, for display only.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / 100
+}
+
+// String formats m as "NGN 1,234.50".
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	whole := strconv.FormatInt(amount/100, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s %s%s.%02d", normalizeCurrency(m.Currency), sign, grouped.String(), amount%100)
+}
+
+// currencyWith picks the currency for the result of combining m and other.
+// The zero Money carries no currency, so it adopts the other operand's.
+func (m Money) currencyWith(other Money) string {
+	if m.Currency == "" {
+		return other.Currency
+	}
+	return m.Currency
+}
+
+// UnmarshalJSON accepts {"amount": 150050, "currency": "NGN"} and fills in
+// DefaultCurrency when the currency is left out.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.New("money must be an object with an integer amount in minor units")
+	}
+	*m = NewMoney(raw.Amount, raw.Currency)
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoneyReadsMajorUnitsExactly(t *testing.T) {
+	cases := map[string]int64{
+		"1500":      150000,
+		"1500.5":    150050,
+		"1,500.05":  150005,
+		"0.10":      10,
+		".99":       99,
+		"-12.34":    -1234,
+		"19.99":     1999,
+		"100000.01": 10000001,
+	}
+
+	for input, want := range cases {
+		m, err := ParseMoney(input, "")
+		assert.NoError(t, err, input)
+		assert.Equal(t, NewMoney(want, DefaultCurrency), m, input)
+	}
+
+	for _, input := range []string{"", "1.005", "abc", "1.-5", "1e3"} {
+		_, err := ParseMoney(input, "NGN")
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMoneyRoundsHalvesAwayFromZero(t *testing.T) {
+	assert.Equal(t, int64(3), NewMoney(5, "NGN").Percent(50).Amount)
+	assert.Equal(t, int64(-3), NewMoney(-5, "NGN").Percent(50).Amount)
+	assert.Equal(t, int64(5000), NewMoney(105000, "NGN").IncludedTax(5).Amount)
+	assert.Equal(t, int64(1999), MoneyFromMajor(19.99, "NGN").Amount)
+	assert.Equal(t, int64(334), NewMoney(1001, "NGN").Share(1, 3).Amount)
+	assert.Equal(t, int64(0), NewMoney(1001, "NGN").Share(1, 0).Amount)
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "NGN 0.00", NewMoney(0, "").String())
+	assert.Equal(t, "NGN 1,234.50", NewMoney(123450, "NGN").String())
+	assert.Equal(t, "USD 1,000,000.00", NewMoney(100000000, "usd").String())
+	assert.Equal(t, "NGN -250.05", NewMoney(-25005, "NGN").String())
+}
+
+func TestMoneyJSONDefaultsCurrency(t *testing.T) {
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 150050}`), &m))
+	assert.Equal(t, NewMoney(150050, "NGN"), m)
+
+	assert.Error(t, json.Unmarshal([]byte(`1500.50`), &m))
+
+	out, _ := json.Marshal(NewMoney(99, "usd"))
+	assert.JSONEq(t, `{"amount": 99, "currency": "USD"}`, string(out))
+}
@@ -6,6 +6,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	DeliveryTypePickup   = "pickup"
+	DeliveryTypeDelivery = "delivery"
+)
+
 type ShippingAddress struct {
 	Street     string `json:"street" bson:"street"`
 	City       string `json:"city" bson:"city"`
@@ -14,13 +19,42 @@ type ShippingAddress struct {
 	PostalCode string `json:"postal_code" bson:"postal_code"`
 }
 
+// OrderItem is a single order line. Everything except ProductID and Quantity
+// is a snapshot of the product taken when the line was priced, so later
+// catalogue changes do not alter past orders.
 type OrderItem struct {
-	ProductID string `json:"product_id" bson:"product_id"`
-	Quantity  int    `json:"quantity" bson:"quantity"`
+	ProductID string  `json:"product_id" bson:"product_id"`
+	Quantity  int     `json:"quantity" bson:"quantity"`
+	Name      string  `json:"name" bson:"name"`
+	Category  string  `json:"category" bson:"category"`
+	ImageURL  string  `json:"image_url" bson:"image_url"`
+	WeightKg  float64 `json:"weight_kg" bson:"weight_kg"`
+	UnitPrice Money   `json:"unit_price" bson:"unit_price"`
+	Subtotal  Money   `json:"subtotal" bson:"subtotal"`
+
+	// Tax on the line. NetAmount is the line excluding tax, whether prices were tax-inclusive or not.
+	TaxRate      float64 `json:"tax_rate" bson:"tax_rate"`
+	TaxInclusive bool    `json:"tax_inclusive" bson:"tax_inclusive"`
+	TaxAmount    Money   `json:"tax_amount" bson:"tax_amount"`
+	NetAmount    Money   `json:"net_amount" bson:"net_amount"`
+}
+
+type OrderStatusChange struct {
+	From      string    `json:"from" bson:"from"`
+	To        string    `json:"to" bson:"to"`
+	Actor     string    `json:"actor" bson:"actor"`
+	Reason    string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at" bson:"changed_at"`
 }
 
 type Order struct {
-	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID string             `json:"user_id" bson:"user_id"`
+
+	// Guest orders are placed without an account; UserID stays empty until the
+	// customer claims the order. Only a hash of the tracking token is stored.
+	Guest             bool   `json:"guest,omitempty" bson:"guest,omitempty"`
+	TrackingTokenHash string `json:"-" bson:"tracking_token_hash,omitempty"`
 
 	CustomerName  string `json:"customer_name" bson:"customer_name"`
 	CustomerEmail string `json:"customer_email" bson:"customer_email"`
@@ -29,11 +63,70 @@ type Order struct {
 	DeliveryType    string          `json:"delivery_type" bson:"delivery_type"`
 	ShippingAddress ShippingAddress `json:"shipping_address" bson:"shipping_address"`
 
+	// Currency every amount on the order is in. ExchangeRate is the rate from the
+	// base currency used when the order was last priced (1 for the base currency).
+	Currency     string  `json:"currency" bson:"currency"`
+	ExchangeRate float64 `json:"exchange_rate" bson:"exchange_rate"`
+
+	CouponCode string             `json:"coupon_code,omitempty" bson:"coupon_code,omitempty"`
+	CouponID   primitive.ObjectID `json:"-" bson:"coupon_id,omitempty"`
+
 	Items         []OrderItem `json:"items" bson:"items"`
-	TotalAmount   float64     `json:"total_amount" bson:"total_amount"`
+	Subtotal      Money       `json:"subtotal" bson:"subtotal"`
+	Discount      Money       `json:"discount" bson:"discount"`
+	TaxAmount     Money       `json:"tax_amount" bson:"tax_amount"`
+	DeliveryFee   Money       `json:"delivery_fee" bson:"delivery_fee"`
+	TotalAmount   Money       `json:"total_amount" bson:"total_amount"`
 	Status        string      `json:"status" bson:"status"`
 	PaymentStatus string      `bson:"payment_status" json:"payment_status"`
 
+	StatusHistory []OrderStatusChange `json:"status_history" bson:"status_history"`
+
+	InvoiceNumber string     `json:"invoice_number,omitempty" bson:"invoice_number,omitempty"`
+	InvoicedAt    *time.Time `json:"invoiced_at,omitempty" bson:"invoiced_at,omitempty"`
+
+	CancellationReason string     `json:"cancellation_reason,omitempty" bson:"cancellation_reason,omitempty"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
+
+// PaidPaymentStatuses are the order payment statuses under which money has been
+// taken for the order, so it must not be paid again.
+var PaidPaymentStatuses = []string{"paid", "partially_paid", "partially_refunded", "refunded"}
+
+// IsPaid reports whether money has been taken for the order.
+func (o Order) IsPaid() bool {
+	for _, status := range PaidPaymentStatuses {
+		if o.PaymentStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderTracking is what the public tracking page shows for an order. It leaves out
+// the customer's contact details and address, since anyone holding the link sees it.
+type OrderTracking struct {
+	OrderID        string          `json:"order_id"`
+	Status         string          `json:"status"`
+	PaymentStatus  string          `json:"payment_status"`
+	DeliveryType   string          `json:"delivery_type"`
+	DeliveryStatus string          `json:"delivery_status,omitempty"`
+	Items          []TrackedItem   `json:"items"`
+	TotalAmount    Money           `json:"total_amount"`
+	History        []TrackingEvent `json:"history"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type TrackedItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+type TrackingEvent struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderIsPaid(t *testing.T) {
+	for status, paid := range map[string]bool{
+		"":                   false,
+		"unpaid":             false,
+		"paid":               true,
+		"partially_paid":     true,
+		"partially_refunded": true,
+		"refunded":           true,
+	} {
+		assert.Equal(t, paid, Order{PaymentStatus: status}.IsPaid(), status)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+// OrderListQuery holds the filters, sort key and page accepted by the admin order listing.
+type OrderListQuery struct {
+	Status        string `form:"status"`
+	PaymentStatus string `form:"payment_status"`
+	DeliveryType  string `form:"delivery_type"`
+	CustomerEmail string `form:"customer_email"`
+	CustomerPhone string `form:"customer_phone"`
+	From          string `form:"from"`
+	To            string `form:"to"`
+	MinTotal      *int64 `form:"min_total"` // minor units
+	MaxTotal      *int64 `form:"max_total"` // minor units
+	Sort          string `form:"sort"`
+	Page          int    `form:"page"`
+	Limit         int    `form:"limit"`
+}
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+}
@@ -1,9 +1,21 @@
 package models
 
 type PaymentRequest struct {
-	UserID        string  `json:"user_id"`
-	OrderID       string  `json:"order_id"`
-	Amount        float64 `json:"amount"`
-	Email         string  `json:"email"`
-	PaymentMethod string  `json:"payment_method" binding:"required"`
+	UserID        string `json:"user_id"`
+	OrderID       string `json:"order_id"`
+	Amount        Money  `json:"amount"`
+	Email         string `json:"email"`
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
+
+// TopUpRequest funds the user's wallet by card.
+type TopUpRequest struct {
+	Amount Money `json:"amount" binding:"required"`
+}
+
+// RefundPaymentRequest is an admin's refund of a charge. Leaving Amount out
+// refunds whatever is left of the charge.
+type RefundPaymentRequest struct {
+	Amount *Money `json:"amount"`
+	Reason string `json:"reason"`
 }
@@ -6,14 +6,65 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	PaymentTypeCharge = "charge"
+	PaymentTypeRefund = "refund"
+	// PaymentTypeTopUp is a card payment into the user's wallet; it has no order.
+	PaymentTypeTopUp = "topup"
+)
+
 type Payment struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	OrderID   string             `json:"order_id" bson:"order_id"`
 	UserID    string             `json:"user_id" bson:"user_id"`
-	Amount    float64            `json:"amount" bson:"amount"`
+	Type      string             `json:"type" bson:"type"`
+	RefundOf  string             `json:"refund_of,omitempty" bson:"refund_of,omitempty"`
+	Amount    Money              `json:"amount" bson:"amount"`
 	Method    string             `json:"method" bson:"method"`
 	Status    string             `json:"status" bson:"status"`
 	Email     string             `json:"email" bson:"email"`
 	Reference string             `json:"reference" bson:"reference"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	Reason    string             `json:"reason,omitempty" bson:"reason,omitempty"`
+
+	// SplitWith links the two legs of a split payment: it holds the ID of the other leg.
+	// The wallet leg stays "held" until the card leg succeeds, and is "released" back to
+	// the wallet if it fails.
+	SplitWith string `json:"split_with,omitempty" bson:"split_with,omitempty"`
+
+	// Set for gateway payments once the provider has opened the transaction.
+	AuthorizationURL string `json:"authorization_url,omitempty" bson:"authorization_url,omitempty"`
+	AccessCode       string `json:"access_code,omitempty" bson:"access_code,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// TransactionRequest asks a payment provider to open a transaction for Amount.
+type TransactionRequest struct {
+	Reference string
+	Email     string
+	Amount    Money
+	Metadata  map[string]string
+}
+
+// TransactionInit is the provider's answer: where the customer pays, and the
+// access code for the provider's inline checkout.
+type TransactionInit struct {
+	AuthorizationURL string
+	AccessCode       string
+	Reference        string
+}
+
+// TransactionStatus is the provider's view of a transaction. Status is the
+// provider's own word for it, e.g. "success", "failed" or "abandoned".
+type TransactionStatus struct {
+	Reference string
+	Status    string
+	Amount    Money
+}
+
+// RefundRequest asks a payment provider to return Amount of the transaction with Reference.
+type RefundRequest struct {
+	Reference string
+	Amount    Money
+	Note      string
 }
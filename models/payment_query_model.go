@@ -0,0 +1,24 @@
+package models
+
+// PaymentListQuery holds the filters and page accepted by the payment listings.
+// Users only ever see their own payments, whatever UserID they send.
+type PaymentListQuery struct {
+	Status  string `form:"status"`
+	Method  string `form:"method"`
+	Type    string `form:"type"`
+	OrderID string `form:"order_id"`
+	UserID  string `form:"user_id"`
+	From    string `form:"from"`
+	To      string `form:"to"`
+	Page    int    `form:"page"`
+	Limit   int    `form:"limit"`
+}
+
+// PaymentTotal sums the payments of one method, type and currency within a listing's filters.
+type PaymentTotal struct {
+	Method   string `json:"method" bson:"method"`
+	Type     string `json:"type" bson:"type"`
+	Currency string `json:"currency" bson:"currency"`
+	Count    int64  `json:"count" bson:"count"`
+	Amount   Money  `json:"amount" bson:"amount"`
+}
@@ -11,10 +11,13 @@ type Product struct {
 	Name        string             `bson:"name" json:"name" binding:"required"`
 	Description string             `bson:"description" json:"description" binding:"required"`
 	Category    string             `bson:"category" json:"category" binding:"required"`
-	Price       float64            `bson:"price" json:"price" binding:"required"`
-	Stock       int                `bson:"stock" json:"stock" binding:"required"`
-	ImageURL    string             `bson:"image_url" json:"image_url"`
-	ImageID     string             `bson:"image_id" json:"image_id"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	Price       Money              `bson:"price" json:"price"`
+	// Prices overrides Price in other currencies; currencies without an override are converted.
+	Prices    []Money   `bson:"prices,omitempty" json:"prices,omitempty"`
+	Stock     int       `bson:"stock" json:"stock" binding:"required"`
+	WeightKg  float64   `bson:"weight_kg" json:"weight_kg"`
+	ImageURL  string    `bson:"image_url" json:"image_url"`
+	ImageID   string    `bson:"image_id" json:"image_id"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
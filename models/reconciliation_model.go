@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of difference between our payment records and the provider's.
+const (
+	// The provider took the money but we never heard; reconciliation settled it.
+	DiscrepancyMissedSuccess = "missed_success"
+	// The charge failed but we never heard; reconciliation marked it failed.
+	DiscrepancyMissedFailure = "missed_failure"
+	// The provider took a different amount from the one we asked for.
+	DiscrepancyAmountMismatch = "amount_mismatch"
+	// The money arrived after the order was cancelled and has to be refunded.
+	DiscrepancyPaidAfterCancel = "paid_after_cancel"
+	// The money arrived for an order another payment had already paid, and has to be refunded.
+	DiscrepancyDoublePayment = "double_payment"
+	// The card part of a split payment succeeded after its wallet part had been released,
+	// so the order is not fully paid.
+	DiscrepancyUnderpaid = "underpaid"
+	// The card part of a split payment stayed pending too long; its wallet hold was released.
+	DiscrepancyHoldExpired = "hold_expired"
+	// The provider still has no outcome, e.g. the customer abandoned checkout.
+	DiscrepancyUnresolved = "unresolved"
+	// The provider could not be asked, or does not know the reference.
+	DiscrepancyProviderError = "provider_error"
+)
+
+type PaymentDiscrepancy struct {
+	PaymentID      string `json:"payment_id" bson:"payment_id"`
+	Reference      string `json:"reference" bson:"reference"`
+	OrderID        string `json:"order_id" bson:"order_id"`
+	Kind           string `json:"kind" bson:"kind"`
+	LocalStatus    string `json:"local_status" bson:"local_status"`
+	ProviderStatus string `json:"provider_status,omitempty" bson:"provider_status,omitempty"`
+	LocalAmount    Money  `json:"local_amount" bson:"local_amount"`
+	ProviderAmount *Money `json:"provider_amount,omitempty" bson:"provider_amount,omitempty"`
+	// Resolved is set when reconciliation already corrected our records.
+	Resolved bool   `json:"resolved" bson:"resolved"`
+	Note     string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+// ReconciliationReport is the outcome of one pass over payments stuck in pending.
+type ReconciliationReport struct {
+	ID            primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	StartedAt     time.Time            `json:"started_at" bson:"started_at"`
+	FinishedAt    time.Time            `json:"finished_at" bson:"finished_at"`
+	Checked       int                  `json:"checked" bson:"checked"`
+	Discrepancies []PaymentDiscrepancy `json:"discrepancies" bson:"discrepancies"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// Ways a reorder can be placed: as a new order, or into the customer's cart.
+const (
+	ReorderModeOrder = "order"
+	ReorderModeCart  = "cart"
+)
+
+// What can differ between an old order line and what is ordered again.
+const (
+	ReorderChangeUnavailable     = "unavailable"
+	ReorderChangeOutOfStock      = "out_of_stock"
+	ReorderChangeQuantityReduced = "quantity_reduced"
+	ReorderChangePriceChanged    = "price_changed"
+)
+
+// ReorderChange reports how one line of the old order was carried over.
+// Quantity is what was reordered; zero means the line was dropped.
+type ReorderChange struct {
+	ProductID         string `json:"product_id"`
+	Name              string `json:"name"`
+	Change            string `json:"change"`
+	RequestedQuantity int    `json:"requested_quantity"`
+	Quantity          int    `json:"quantity"`
+	OldPrice          *Money `json:"old_price,omitempty"`
+	NewPrice          *Money `json:"new_price,omitempty"`
+}
+
+type ReorderResult struct {
+	Mode    string          `json:"mode"`
+	Order   *Order          `json:"order,omitempty"`
+	Cart    *Cart           `json:"cart,omitempty"`
+	Changes []ReorderChange `json:"changes"`
+}
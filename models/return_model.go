@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A return moves from requested to approved or rejected; approved returns are
+// received once the goods are back, which restocks them and issues the refund.
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+)
+
+// Where a return is refunded to: the customer's wallet, or the method the order was paid with.
+const (
+	RefundMethodWallet   = "wallet"
+	RefundMethodOriginal = "original"
+)
+
+// ReturnItem is a returned quantity of one order line. RefundAmount is that
+// quantity's share of what was paid for the line.
+type ReturnItem struct {
+	ProductID    string `json:"product_id" bson:"product_id"`
+	Name         string `json:"name" bson:"name"`
+	Quantity     int    `json:"quantity" bson:"quantity"`
+	RefundAmount Money  `json:"refund_amount" bson:"refund_amount"`
+}
+
+type ReturnPhoto struct {
+	URL      string `json:"url" bson:"url"`
+	PublicID string `json:"-" bson:"public_id"`
+}
+
+type Return struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	OrderID string             `json:"order_id" bson:"order_id"`
+	UserID  string             `json:"user_id" bson:"user_id"`
+
+	Items  []ReturnItem  `json:"items" bson:"items"`
+	Reason string        `json:"reason" bson:"reason"`
+	Photos []ReturnPhoto `json:"photos" bson:"photos"`
+
+	RefundMethod string `json:"refund_method" bson:"refund_method"`
+	RefundAmount Money  `json:"refund_amount" bson:"refund_amount"`
+	// RefundIDs are the refund payments issued when the return was received, one for
+	// each charge of the order it was refunded against.
+	RefundIDs []string `json:"refund_ids,omitempty" bson:"refund_ids,omitempty"`
+
+	Status     string     `json:"status" bson:"status"`
+	AdminNote  string     `json:"admin_note,omitempty" bson:"admin_note,omitempty"`
+	ReviewedBy string     `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	ReceivedAt *time.Time `json:"received_at,omitempty" bson:"received_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
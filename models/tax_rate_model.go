@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaxRate is a VAT rate applied to order lines. Empty Category or State match any value;
+// when several rates match a line, the one naming both wins, then category, then state.
+type TaxRate struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name     string             `json:"name" bson:"name" binding:"required"`
+	Category string             `json:"category" bson:"category"`
+	State    string             `json:"state" bson:"state"`
+
+	// Rate is a percentage, e.g. 7.5 for Nigerian VAT.
+	Rate float64 `json:"rate" bson:"rate" binding:"gte=0,lte=100"`
+	// Inclusive means catalogue prices already contain the tax.
+	Inclusive bool `json:"inclusive" bson:"inclusive"`
+
+	Active    bool      `json:"active" bson:"active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// TaxReportRow is the tax collected for one category and shipping state.
+type TaxReportRow struct {
+	Category   string `json:"category" bson:"category"`
+	State      string `json:"state" bson:"state"`
+	Orders     int    `json:"orders" bson:"orders"`
+	NetSales   Money  `json:"net_sales" bson:"net_sales"`
+	TaxAmount  Money  `json:"tax_amount" bson:"tax_amount"`
+	GrossSales Money  `json:"gross_sales" bson:"gross_sales"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// What became of a received webhook event.
+const (
+	WebhookStatusReceived  = "received"
+	WebhookStatusProcessed = "processed"
+	WebhookStatusIgnored   = "ignored"
+	WebhookStatusFailed    = "failed"
+)
+
+// WebhookEvent is a payment provider callback exactly as it arrived, kept so it
+// can be inspected and replayed.
+type WebhookEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Provider  string             `json:"provider" bson:"provider"`
+	Event     string             `json:"event" bson:"event"`
+	Reference string             `json:"reference" bson:"reference"`
+	Payload   string             `json:"payload" bson:"payload"`
+	Status    string             `json:"status" bson:"status"`
+	Error     string             `json:"error,omitempty" bson:"error,omitempty"`
+	Attempts  int                `json:"attempts" bson:"attempts"`
+
+	ReceivedAt  time.Time  `json:"received_at" bson:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty" bson:"processed_at,omitempty"`
+}
+
+// Payment events the provider reports, named as Paystack names them.
+const (
+	PaymentEventChargeSuccess   = "charge.success"
+	PaymentEventChargeFailed    = "charge.failed"
+	PaymentEventRefundPending   = "refund.pending"
+	PaymentEventRefundProcessed = "refund.processed"
+	PaymentEventRefundFailed    = "refund.failed"
+)
+
+// PaymentEvent is a provider callback reduced to what we act on. Reference is
+// always the reference of the original charge.
+type PaymentEvent struct {
+	Type      string
+	Reference string
+	Amount    Money
+}
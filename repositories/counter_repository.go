@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CounterRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCounterRepository(collection *mongo.Collection) *CounterRepository {
+	return &CounterRepository{collection}
+}
+
+// Next atomically increments the named sequence and returns its new value, starting at 1.
+func (r *CounterRepository) Next(ctx context.Context, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": name},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+
+	return counter.Seq, err
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CouponRepository struct {
+	collection *mongo.Collection
+	usages     *mongo.Collection
+}
+
+func NewCouponRepository(collection *mongo.Collection, usages *mongo.Collection) *CouponRepository {
+	return &CouponRepository{collection, usages}
+}
+
+// EnsureIndexes keeps coupon codes unique and gives every (coupon, user) pair a single usage counter.
+func (r *CouponRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.usages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "coupon_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *CouponRepository) Create(coupon models.Coupon) (models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, coupon)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Coupon{}, errors.New("coupon code already exists")
+	}
+	return coupon, err
+}
+
+func (r *CouponRepository) FindAll() ([]models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	coupons := []models.Coupon{}
+	err = cursor.All(ctx, &coupons)
+	return coupons, err
+}
+
+func (r *CouponRepository) FindByID(id string) (models.Coupon, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Coupon{}, errors.New("invalid coupon id")
+	}
+
+	return r.findOne(bson.M{"_id": oid})
+}
+
+func (r *CouponRepository) FindByCode(code string) (models.Coupon, error) {
+	return r.findOne(bson.M{"code": code})
+}
+
+func (r *CouponRepository) findOne(filter bson.M) (models.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var coupon models.Coupon
+	err := r.collection.FindOne(ctx, filter).Decode(&coupon)
+	if err == mongo.ErrNoDocuments {
+		return models.Coupon{}, errors.New("coupon not found")
+	}
+	return coupon, err
+}
+
+// Update replaces the coupon's settings; the redemption counter is left alone.
+func (r *CouponRepository) Update(id string, coupon models.Coupon) (models.Coupon, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Coupon{}, errors.New("invalid coupon id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"code":            coupon.Code,
+			"type":            coupon.Type,
+			"value":           coupon.Value,
+			"amount":          coupon.Amount,
+			"min_order_value": coupon.MinOrderValue,
+			"starts_at":       coupon.StartsAt,
+			"expires_at":      coupon.ExpiresAt,
+			"usage_limit":     coupon.UsageLimit,
+			"per_user_limit":  coupon.PerUserLimit,
+			"categories":      coupon.Categories,
+			"product_ids":     coupon.ProductIDs,
+			"active":          coupon.Active,
+			"updated_at":      coupon.UpdatedAt,
+		},
+	}
+
+	var updated models.Coupon
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": oid},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Coupon{}, errors.New("coupon not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return models.Coupon{}, errors.New("coupon code already exists")
+		}
+		return models.Coupon{}, err
+	}
+	return updated, nil
+}
+
+func (r *CouponRepository) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid coupon id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("coupon not found")
+	}
+	return nil
+}
+
+// Redeem counts one use of the coupon by userID. Both the global and the per-user
+// limit are enforced by the updates themselves, so concurrent checkouts cannot overshoot them.
+func (r *CouponRepository) Redeem(ctx context.Context, coupon models.Coupon, userID string) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":    coupon.ID,
+			"active": true,
+			"$or": bson.A{
+				bson.M{"usage_limit": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$usage_limit"}}},
+			},
+		},
+		bson.M{"$inc": bson.M{"used_count": 1}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("coupon usage limit reached")
+	}
+
+	filter := bson.M{"coupon_id": coupon.ID, "user_id": userID}
+	if coupon.PerUserLimit > 0 {
+		filter["count"] = bson.M{"$lt": coupon.PerUserLimit}
+	}
+
+	// When the user is already at the limit the filter misses, the upsert collides
+	// with their existing counter on the unique index and the redemption is refused.
+	_, err = r.usages.UpdateOne(
+		ctx,
+		filter,
+		bson.M{"$inc": bson.M{"count": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("you have already used this coupon")
+	}
+	return err
+}
+
+// Release gives back a redemption, e.g. when the order that used it is cancelled.
+func (r *CouponRepository) Release(ctx context.Context, couponID primitive.ObjectID, userID string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": couponID, "used_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"used_count": -1}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.usages.UpdateOne(
+		ctx,
+		bson.M{"coupon_id": couponID, "user_id": userID, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
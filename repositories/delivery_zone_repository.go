@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DeliveryZoneRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDeliveryZoneRepository(collection *mongo.Collection) *DeliveryZoneRepository {
+	return &DeliveryZoneRepository{collection}
+}
+
+func (r *DeliveryZoneRepository) Create(zone models.DeliveryZone) (models.DeliveryZone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, zone)
+	return zone, err
+}
+
+func (r *DeliveryZoneRepository) FindAll() ([]models.DeliveryZone, error) {
+	return r.find(bson.M{})
+}
+
+func (r *DeliveryZoneRepository) FindActive() ([]models.DeliveryZone, error) {
+	return r.find(bson.M{"active": true})
+}
+
+func (r *DeliveryZoneRepository) find(filter bson.M) ([]models.DeliveryZone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zones := []models.DeliveryZone{}
+	err = cursor.All(ctx, &zones)
+	return zones, err
+}
+
+func (r *DeliveryZoneRepository) Update(id string, zone models.DeliveryZone) (models.DeliveryZone, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.DeliveryZone{}, errors.New("invalid delivery zone id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":             zone.Name,
+			"country":          zone.Country,
+			"state":            zone.State,
+			"city":             zone.City,
+			"postal_prefix":    zone.PostalPrefix,
+			"fee_type":         zone.FeeType,
+			"flat_fee":         zone.FlatFee,
+			"base_fee":         zone.BaseFee,
+			"per_kg_fee":       zone.PerKgFee,
+			"free_over_amount": zone.FreeOverAmount,
+			"active":           zone.Active,
+			"updated_at":       zone.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, oid, update)
+	if err != nil {
+		return models.DeliveryZone{}, err
+	}
+	if result.MatchedCount == 0 {
+		return models.DeliveryZone{}, errors.New("delivery zone not found")
+	}
+
+	var updated models.DeliveryZone
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&updated)
+	return updated, err
+}
+
+func (r *DeliveryZoneRepository) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid delivery zone id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("delivery zone not found")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExchangeRateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewExchangeRateRepository(collection *mongo.Collection) *ExchangeRateRepository {
+	return &ExchangeRateRepository{collection}
+}
+
+// EnsureIndexes allows a single rate per currency.
+func (r *ExchangeRateRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "currency", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *ExchangeRateRepository) Create(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, rate)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ExchangeRate{}, errors.New("exchange rate for this currency already exists")
+	}
+	return rate, err
+}
+
+func (r *ExchangeRateRepository) FindAll() ([]models.ExchangeRate, error) {
+	return r.find(bson.M{})
+}
+
+func (r *ExchangeRateRepository) FindActive() ([]models.ExchangeRate, error) {
+	return r.find(bson.M{"active": true})
+}
+
+func (r *ExchangeRateRepository) find(filter bson.M) ([]models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.ExchangeRate{}
+	err = cursor.All(ctx, &rates)
+	return rates, err
+}
+
+func (r *ExchangeRateRepository) Update(id string, rate models.ExchangeRate) (models.ExchangeRate, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.ExchangeRate{}, errors.New("invalid exchange rate id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"currency":   rate.Currency,
+			"rate":       rate.Rate,
+			"active":     rate.Active,
+			"updated_at": rate.UpdatedAt,
+		},
+	}
+
+	var updated models.ExchangeRate
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": oid},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return models.ExchangeRate{}, errors.New("exchange rate not found")
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ExchangeRate{}, errors.New("exchange rate for this currency already exists")
+	}
+	return updated, err
+}
+
+func (r *ExchangeRateRepository) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid exchange rate id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("exchange rate not found")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IdempotencyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewIdempotencyRepository(collection *mongo.Collection) *IdempotencyRepository {
+	return &IdempotencyRepository{collection}
+}
+
+// EnsureIndexes makes keys unique per user and lets MongoDB drop them once expires_at has passed.
+func (r *IdempotencyRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// Reserve claims record.Key for record.UserID. If the key is already held it
+// returns the stored record and false instead. Expired keys that the TTL
+// monitor has not removed yet are replaced, and so are reservations whose lease
+// ran out before their request stored a response.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyKey) (models.IdempotencyKey, bool, error) {
+	record.ID = primitive.NewObjectID()
+	record.Status = models.IdempotencyInProgress
+
+	now := time.Now()
+	_, err := r.collection.DeleteOne(ctx, bson.M{
+		"user_id": record.UserID,
+		"key":     record.Key,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lte": now}},
+			bson.M{"status": models.IdempotencyInProgress, "locked_until": bson.M{"$lte": now}},
+		},
+	})
+	if err != nil {
+		return models.IdempotencyKey{}, false, err
+	}
+
+	_, err = r.collection.InsertOne(ctx, record)
+	if err == nil {
+		return record, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return models.IdempotencyKey{}, false, err
+	}
+
+	var existing models.IdempotencyKey
+	err = r.collection.FindOne(ctx, bson.M{"user_id": record.UserID, "key": record.Key}).Decode(&existing)
+	return existing, false, err
+}
+
+// Complete stores the response that retries of the request will receive. A request
+// whose key was taken over after its lease ran out finds nothing to update.
+func (r *IdempotencyRepository) Complete(ctx context.Context, id primitive.ObjectID, code int, body []byte, contentType string) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{
+		"status":                models.IdempotencyCompleted,
+		"response_code":         code,
+		"response_body":         body,
+		"response_content_type": contentType,
+	}})
+	return err
+}
+
+// Release forgets a reserved key so that the request may be retried.
+func (r *IdempotencyRepository) Release(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LeaseRepository hands out named, time-limited leases so that only one
+// application instance at a time runs a given background job.
+type LeaseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLeaseRepository(collection *mongo.Collection) *LeaseRepository {
+	return &LeaseRepository{collection}
+}
+
+// Acquire takes or renews the lease name for holder until ttl from now.
+// It reports false while another holder's lease has not yet expired.
+func (r *LeaseRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"holder":      holder,
+		"acquired_at": now,
+		"expires_at":  now.Add(ttl),
+	}}
+
+	// When someone else holds the lease the filter does not match, the upsert tries to
+	// insert a second document with the same _id and fails with a duplicate key.
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release gives the lease up early if holder still owns it.
+func (r *LeaseRepository) Release(ctx context.Context, name, holder string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": name, "holder": holder},
+		bson.M{"$set": bson.M{"expires_at": time.Now()}},
+	)
+	return err
+}
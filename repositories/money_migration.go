@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// moneyFields lists, per collection, the top-level fields that hold amounts.
+var moneyFields = map[string][]string{
+	"products":       {"price"},
+	"orders":         {"subtotal", "discount", "tax_amount", "delivery_fee", "total_amount"},
+	"payments":       {"amount"},
+	"wallets":        {"balance"},
+	"delivery_zones": {"flat_fee", "base_fee", "per_kg_fee", "free_over_amount"},
+	"coupons":        {"min_order_value"},
+}
+
+// orderItemMoneyFields are the amounts on every element of orders.items.
+var orderItemMoneyFields = []string{"unit_price", "subtotal", "tax_amount", "net_amount"}
+
+// MigrateMoney rewrites amounts stored as floating-point naira into models.Money
+// sub-documents in kobo. Only fields that are still plain numbers are touched, so it
+// is safe to run on every start. Conversion rounds to the nearest kobo, halves away
+// from zero, the same rule models.Money uses.
+func MigrateMoney(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for collection, fields := range moneyFields {
+		legacy := bson.A{}
+		set := bson.M{}
+		for _, field := range fields {
+			legacy = append(legacy, bson.M{field: bson.M{"$type": "number"}})
+			set[field] = convertIfNumber("$" + field)
+		}
+
+		pipeline := mongo.Pipeline{{{Key: "$set", Value: set}}}
+
+		switch collection {
+		case "orders":
+			itemSet := bson.M{}
+			for _, field := range orderItemMoneyFields {
+				legacy = append(legacy, bson.M{"items." + field: bson.M{"$type": "number"}})
+				itemSet[field] = convertIfNumber("$$item." + field)
+			}
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.M{
+				"items": bson.M{"$cond": bson.A{
+					bson.M{"$isArray": "$items"},
+					bson.M{"$map": bson.M{
+						"input": "$items",
+						"as":    "item",
+						"in":    bson.M{"$mergeObjects": bson.A{"$$item", itemSet}},
+					}},
+					"$items",
+				}},
+			}}})
+
+		case "coupons":
+			// Fixed coupons used to keep their amount in value; it now lives in amount.
+			fixed := bson.M{"type": models.CouponFixed, "value": bson.M{"$type": "number"}}
+			legacy = append(legacy, fixed)
+			isFixed := bson.M{"$eq": bson.A{"$type", models.CouponFixed}}
+			pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.M{
+				"amount": bson.M{"$cond": bson.A{isFixed, convertIfNumber("$value"), "$amount"}},
+				"value":  bson.M{"$cond": bson.A{isFixed, "$$REMOVE", "$value"}},
+			}}})
+		}
+
+		_, err := db.Collection(collection).UpdateMany(ctx, bson.M{"$or": legacy}, pipeline)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Orders placed before checkout took a currency are in the base currency.
+	_, err := db.Collection("orders").UpdateMany(ctx,
+		bson.M{"currency": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"currency": models.DefaultCurrency, "exchange_rate": 1}},
+	)
+	return err
+}
+
+// convertIfNumber turns a numeric naira amount into {amount: kobo, currency} and
+// leaves anything else (already migrated, or missing) as it is.
+func convertIfNumber(field string) bson.M {
+	kobo := bson.M{"$multiply": bson.A{field, 100}}
+	rounded := bson.M{"$multiply": bson.A{
+		bson.M{"$cond": bson.A{bson.M{"$lt": bson.A{field, 0}}, -1, 1}},
+		bson.M{"$floor": bson.M{"$add": bson.A{bson.M{"$abs": kobo}, 0.5}}},
+	}}
+
+	return bson.M{"$cond": bson.A{
+		bson.M{"$isNumber": field},
+		bson.M{
+			"amount":   bson.M{"$toLong": rounded},
+			"currency": models.DefaultCurrency,
+		},
+		field,
+	}}
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderRepository struct {
@@ -19,20 +20,59 @@ func NewOrderRepository(collection *mongo.Collection) *OrderRepository {
 	return &OrderRepository{collection}
 }
 
-func (r *OrderRepository) CreateOrder(order models.Order) (models.Order, error) {
-	order.ID = primitive.NewObjectID()
-	_, err := r.collection.InsertOne(context.Background(), order)
+func (r *OrderRepository) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, order)
 	return order, err
 }
 
 func (r *OrderRepository) FindOrderByID(id string) (models.Order, error) {
+	return r.FindOrder(context.Background(), id)
+}
+
+// FindOrder is FindOrderByID for reads that belong to a transaction.
+func (r *OrderRepository) FindOrder(ctx context.Context, id string) (models.Order, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Order{}, errors.New("Invalid order id")
+	}
+
+	var order models.Order
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Order{}, errors.New("Order not found")
+		}
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+// FindUserOrder returns the order only if it belongs to userID, so callers
+// cannot tell someone else's order apart from a missing one.
+func (r *OrderRepository) FindUserOrder(id string, userID string) (models.Order, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return models.Order{}, errors.New("Invalid order id")
 	}
 
 	var order models.Order
-	err = r.collection.FindOne(context.Background(), bson.M{"_id": oid}).Decode(&order)
+	err = r.collection.FindOne(context.Background(), bson.M{"_id": oid, "user_id": userID}).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Order{}, errors.New("Order not found")
+		}
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+// FindByTrackingTokenHash returns the order whose tracking token hashes to hash.
+func (r *OrderRepository) FindByTrackingTokenHash(hash string) (models.Order, error) {
+	var order models.Order
+	err := r.collection.FindOne(context.Background(), bson.M{"tracking_token_hash": hash}).Decode(&order)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return models.Order{}, errors.New("Order not found")
@@ -56,21 +96,131 @@ func (r *OrderRepository) FindOrdersByUserID(userID string) ([]models.Order, err
 	return orders, nil
 }
 
-func (r *OrderRepository) FindAll() ([]models.Order, error) {
-	cursor, err := r.collection.Find(context.Background(), bson.M{})
+// FindUnpaidBefore returns up to limit orders, oldest first, that are still open and
+// unpaid and were placed before cutoff.
+func (r *OrderRepository) FindUnpaidBefore(ctx context.Context, statuses []string, cutoff time.Time, limit int64) ([]models.Order, error) {
+	filter := bson.M{
+		"status":         bson.M{"$in": statuses},
+		"payment_status": "unpaid",
+		"created_at":     bson.M{"$lt": cutoff},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetLimit(limit))
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
-	var orders []models.Order
-	if err := cursor.All(context.Background(), &orders); err != nil {
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
 
-func (r *OrderRepository) UpdateOrder(id string, order models.Order) (models.Order, error) {
+// FindPage returns one page of orders matching filter together with the total number of matches.
+func (r *OrderRepository) FindPage(filter bson.M, sort bson.D, skip, limit int64) ([]models.Order, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(sort).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	orders := []models.Order{}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+// TaxSummary totals the tax on orders matching filter, per line category, shipping state and currency.
+func (r *OrderRepository) TaxSummary(filter bson.M) ([]models.TaxReportRow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"category": "$items.category",
+				"state":    "$shipping_address.state",
+				"currency": "$total_amount.currency",
+			},
+			"orders":     bson.M{"$addToSet": "$_id"},
+			"net_sales":  bson.M{"$sum": "$items.net_amount.amount"},
+			"tax_amount": bson.M{"$sum": "$items.tax_amount.amount"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":      0,
+			"category": "$_id.category",
+			"state":    "$_id.state",
+			"orders":   bson.M{"$size": "$orders"},
+			"net_sales": bson.M{
+				"amount":   bson.M{"$toLong": "$net_sales"},
+				"currency": "$_id.currency",
+			},
+			"tax_amount": bson.M{
+				"amount":   bson.M{"$toLong": "$tax_amount"},
+				"currency": "$_id.currency",
+			},
+			"gross_sales": bson.M{
+				"amount":   bson.M{"$toLong": bson.M{"$add": bson.A{"$net_sales", "$tax_amount"}}},
+				"currency": "$_id.currency",
+			},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "state", Value: 1}, {Key: "category", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []models.TaxReportRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// EnsureIndexes creates the indexes backing the user and admin order listings.
+func (r *OrderRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "payment_status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "delivery_type", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "total_amount.amount", Value: 1}}},
+		{Keys: bson.D{{Key: "customer_email", Value: 1}}},
+		{Keys: bson.D{{Key: "customer_phone", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "tracking_token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	})
+	return err
+}
+
+// UpdateOrder rewrites the editable fields of an order that is still in currentStatus
+// and unpaid, so an order paid or moved on in the meantime is left alone.
+func (r *OrderRepository) UpdateOrder(ctx context.Context, id string, userID string, currentStatus string, order models.Order) (models.Order, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return models.Order{}, errors.New("invalid order id")
@@ -84,62 +234,159 @@ func (r *OrderRepository) UpdateOrder(id string, order models.Order) (models.Ord
 			"delivery_type":    order.DeliveryType,
 			"shipping_address": order.ShippingAddress,
 			"items":            order.Items,
+			"subtotal":         order.Subtotal,
+			"discount":         order.Discount,
+			"tax_amount":       order.TaxAmount,
+			"exchange_rate":    order.ExchangeRate,
+			"delivery_fee":     order.DeliveryFee,
 			"total_amount":     order.TotalAmount,
-			"status":           order.Status,
+			"updated_at":       order.UpdatedAt,
 		},
 	}
 
-	result, err := r.collection.UpdateOne(context.Background(), bson.M{"_id": oid}, update)
+	var updated models.Order
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"_id":            oid,
+			"user_id":        userID,
+			"status":         currentStatus,
+			"payment_status": bson.M{"$nin": models.PaidPaymentStatuses},
+		},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Order{}, errors.New("only pending, unpaid orders can be updated")
+		}
 		return models.Order{}, err
 	}
-	if result.MatchedCount == 0 {
-		return models.Order{}, errors.New("order not found")
-	}
 
-	order.ID = oid
-	return order, nil
+	return updated, nil
 }
 
-func (r *OrderRepository) UpdateOrderStatus(id string, status string) error {
+// TransitionStatus moves an order out of currentStatus and records the change in its history.
+// The update only applies if the order is still in currentStatus, so concurrent transitions
+// cannot silently overwrite each other.
+func (r *OrderRepository) TransitionStatus(ctx context.Context, id string, currentStatus string, change models.OrderStatusChange) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("Invalid order id")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	update := bson.M{
 		"$set": bson.M{
-			"status":     status,
-			"updated_at": time.Now(),
+			"status":     change.To,
+			"updated_at": change.ChangedAt,
 		},
+		"$push": bson.M{"status_history": change},
 	}
 
-	result, err := r.collection.UpdateByID(ctx, oid, update)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": oid, "status": currentStatus}, update)
 	if err != nil {
 		return errors.New("failed to update order status")
 	}
 	if result.MatchedCount == 0 {
-		return errors.New("Order not found")
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": oid})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("Order not found")
+		}
+		return errors.New("order status was changed by another request")
 	}
 
 	return nil
 }
 
-func (r *OrderRepository) DeleteOrder(id string) error {
+// SetInvoiceNumber stores number on the order unless it already has one.
+// It reports whether this call assigned it.
+func (r *OrderRepository) SetInvoiceNumber(ctx context.Context, id primitive.ObjectID, number string, at time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "invoice_number": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"invoice_number": number, "invoiced_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ClaimGuestOrder attaches an unclaimed guest order to userID.
+// It reports whether this call claimed it.
+func (r *OrderRepository) ClaimGuestOrder(ctx context.Context, id primitive.ObjectID, userID string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "guest": true, "user_id": ""},
+		bson.M{"$set": bson.M{"user_id": userID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *OrderRepository) SetPaymentStatus(ctx context.Context, id string, paymentStatus string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("Invalid order id")
 	}
 
-	result, err := r.collection.DeleteOne(context.Background(), bson.M{"_id": oid})
+	_, err = r.collection.UpdateByID(ctx, oid, bson.M{
+		"$set": bson.M{"payment_status": paymentStatus, "updated_at": time.Now()},
+	})
+	return err
+}
+
+// MarkPaid sets the order's payment status to paid unless money has already been
+// taken for it, in which case it fails with "order has already been paid", or the
+// order has been cancelled, in which case it fails with "order has been cancelled".
+func (r *OrderRepository) MarkPaid(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("Invalid order id")
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{
+			"_id":            oid,
+			"status":         bson.M{"$ne": "cancelled"},
+			"payment_status": bson.M{"$nin": models.PaidPaymentStatuses},
+		},
+		bson.M{"$set": bson.M{"payment_status": "paid", "updated_at": time.Now()}},
+	)
 	if err != nil {
 		return err
 	}
-	if result.DeletedCount == 0 {
-		return errors.New("Order not found")
+	if result.MatchedCount > 0 {
+		return nil
 	}
-	return nil
+
+	order, err := r.FindOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if order.Status == "cancelled" {
+		return errors.New("order has been cancelled")
+	}
+	return errors.New("order has already been paid")
+}
+
+// MarkCancelled records why and when an order was cancelled.
+func (r *OrderRepository) MarkCancelled(ctx context.Context, id string, reason string, at time.Time) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("Invalid order id")
+	}
+
+	_, err = r.collection.UpdateByID(ctx, oid, bson.M{
+		"$set": bson.M{
+			"cancellation_reason": reason,
+			"cancelled_at":        at,
+		},
+	})
+	return err
 }
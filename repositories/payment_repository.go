@@ -3,9 +3,13 @@ package repositories
 import (
 	"adhomes-backend/models"
 	"context"
+	"errors"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type PaymentRepository struct {
@@ -16,8 +20,201 @@ func NewPaymentRepository(collection *mongo.Collection) *PaymentRepository {
 	return &PaymentRepository{collection}
 }
 
-func (r *PaymentRepository) Create(payment models.Payment) (models.Payment, error) {
+// EnsureIndexes makes references unique, since provider callbacks find payments by them.
+func (r *PaymentRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "reference", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "order_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "method", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	return err
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+	if payment.Type == "" {
+		payment.Type = models.PaymentTypeCharge
+	}
 	payment.CreatedAt = time.Now()
-	_, err := r.collection.InsertOne(context.Background(), payment)
+	_, err := r.collection.InsertOne(ctx, payment)
+	return payment, err
+}
+
+func (r *PaymentRepository) FindByOrderID(ctx context.Context, orderID string) ([]models.Payment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.Payment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// FindPage returns one page of the payments matching filter, newest first, and how many match in all.
+func (r *PaymentRepository) FindPage(ctx context.Context, filter bson.M, skip, limit int64) ([]models.Payment, int64, error) {
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.Payment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, 0, err
+	}
+	return payments, total, nil
+}
+
+// TotalsByMethod sums the payments matching filter per method, type and currency.
+func (r *PaymentRepository) TotalsByMethod(ctx context.Context, filter bson.M) ([]models.PaymentTotal, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"method":   "$method",
+				"type":     "$type",
+				"currency": "$amount.currency",
+			},
+			"count":  bson.M{"$sum": 1},
+			"amount": bson.M{"$sum": "$amount.amount"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":             0,
+			"method":          "$_id.method",
+			"type":            "$_id.type",
+			"currency":        "$_id.currency",
+			"count":           1,
+			"amount.amount":   "$amount",
+			"amount.currency": "$_id.currency",
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "method", Value: 1}, {Key: "type", Value: 1}, {Key: "currency", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	totals := []models.PaymentTotal{}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (r *PaymentRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	result, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"status": status}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("payment not found")
+	}
+	return nil
+}
+
+func (r *PaymentRepository) FindByID(ctx context.Context, id string) (models.Payment, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Payment{}, errors.New("Invalid payment id")
+	}
+
+	var payment models.Payment
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&payment)
+	if err == mongo.ErrNoDocuments {
+		return models.Payment{}, errors.New("payment not found")
+	}
 	return payment, err
 }
+
+func (r *PaymentRepository) FindByReference(ctx context.Context, reference string) (models.Payment, error) {
+	var payment models.Payment
+	err := r.collection.FindOne(ctx, bson.M{"reference": reference}).Decode(&payment)
+	if err == mongo.ErrNoDocuments {
+		return models.Payment{}, errors.New("payment not found")
+	}
+	return payment, err
+}
+
+// FindPendingBefore returns up to limit pending charges and wallet top-ups made with
+// method before cutoff, oldest first.
+func (r *PaymentRepository) FindPendingBefore(ctx context.Context, method string, cutoff time.Time, limit int64) ([]models.Payment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"type":       bson.M{"$in": []string{models.PaymentTypeCharge, models.PaymentTypeTopUp}},
+		"method":     method,
+		"status":     "pending",
+		"created_at": bson.M{"$lt": cutoff},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.Payment{}
+	err = cursor.All(ctx, &payments)
+	return payments, err
+}
+
+// UpdateStatusFrom moves the payment to status only while it is in one of from.
+// It reports whether this call changed it.
+func (r *PaymentRepository) UpdateStatusFrom(ctx context.Context, id primitive.ObjectID, from []string, status string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": from}},
+		bson.M{"$set": bson.M{"status": status}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// Lock writes to the payment inside a transaction so that concurrent transactions
+// working on it conflict and are retried, rather than both acting on the same reads.
+func (r *PaymentRepository) Lock(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"locked_at": time.Now()}})
+	return err
+}
+
+// SetAuthorization stores where the customer completes a gateway payment.
+func (r *PaymentRepository) SetAuthorization(ctx context.Context, id primitive.ObjectID, authorizationURL string, accessCode string) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{
+		"authorization_url": authorizationURL,
+		"access_code":       accessCode,
+	}})
+	return err
+}
+
+// AssignUser gives userID the payments made on an order before it had an owner.
+func (r *PaymentRepository) AssignUser(ctx context.Context, orderID string, userID string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"order_id": orderID, "user_id": ""},
+		bson.M{"$set": bson.M{"user_id": userID}},
+	)
+	return err
+}
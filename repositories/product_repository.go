@@ -92,3 +92,36 @@ func (r *ProductRepository) FindByID(id primitive.ObjectID) (*models.Product, er
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
 	return &product, err
 }
+
+// STOCK
+// DecrementStock takes qty units out of stock, but only if at least qty are available.
+func (r *ProductRepository) DecrementStock(ctx context.Context, id primitive.ObjectID, qty int) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "stock": bson.M{"$gte": qty}},
+		bson.M{
+			"$inc": bson.M{"stock": -qty},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("insufficient stock")
+	}
+	return nil
+}
+
+// IncrementStock puts qty units back into stock.
+func (r *ProductRepository) IncrementStock(ctx context.Context, id primitive.ObjectID, qty int) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$inc": bson.M{"stock": qty},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReconciliationReportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReconciliationReportRepository(collection *mongo.Collection) *ReconciliationReportRepository {
+	return &ReconciliationReportRepository{collection}
+}
+
+func (r *ReconciliationReportRepository) Create(ctx context.Context, report models.ReconciliationReport) (models.ReconciliationReport, error) {
+	if report.ID.IsZero() {
+		report.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, report)
+	return report, err
+}
+
+// FindRecent returns the latest reports, newest first.
+func (r *ReconciliationReportRepository) FindRecent(limit int64) ([]models.ReconciliationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.ReconciliationReport{}
+	err = cursor.All(ctx, &reports)
+	return reports, err
+}
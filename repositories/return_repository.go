@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReturnRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReturnRepository(collection *mongo.Collection) *ReturnRepository {
+	return &ReturnRepository{collection}
+}
+
+// EnsureIndexes creates the indexes backing the customer and admin return listings.
+func (r *ReturnRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "order_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+func (r *ReturnRepository) Create(ctx context.Context, ret models.Return) (models.Return, error) {
+	if ret.ID.IsZero() {
+		ret.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, ret)
+	return ret, err
+}
+
+func (r *ReturnRepository) FindByID(id string) (models.Return, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Return{}, errors.New("Invalid return id")
+	}
+	return r.findOne(bson.M{"_id": oid})
+}
+
+// FindUserReturn returns the return only if it belongs to userID.
+func (r *ReturnRepository) FindUserReturn(id string, userID string) (models.Return, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Return{}, errors.New("Invalid return id")
+	}
+	return r.findOne(bson.M{"_id": oid, "user_id": userID})
+}
+
+func (r *ReturnRepository) findOne(filter bson.M) (models.Return, error) {
+	var ret models.Return
+	err := r.collection.FindOne(context.Background(), filter).Decode(&ret)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Return{}, errors.New("Return not found")
+		}
+		return models.Return{}, err
+	}
+	return ret, nil
+}
+
+func (r *ReturnRepository) FindByUserID(userID string) ([]models.Return, error) {
+	return r.find(context.Background(), bson.M{"user_id": userID})
+}
+
+// FindAll lists returns, newest first, optionally only those in status.
+func (r *ReturnRepository) FindAll(status string) ([]models.Return, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	return r.find(context.Background(), filter)
+}
+
+// FindActiveByOrderID returns the order's returns that were not rejected.
+func (r *ReturnRepository) FindActiveByOrderID(ctx context.Context, orderID string) ([]models.Return, error) {
+	return r.find(ctx, bson.M{"order_id": orderID, "status": bson.M{"$ne": models.ReturnStatusRejected}})
+}
+
+func (r *ReturnRepository) find(ctx context.Context, filter bson.M) ([]models.Return, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	returns := []models.Return{}
+	err = cursor.All(ctx, &returns)
+	return returns, err
+}
+
+// Transition applies set to the return provided it is still in status from, so two
+// admins acting on the same return cannot both succeed.
+func (r *ReturnRepository) Transition(ctx context.Context, id primitive.ObjectID, from string, set bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("return has already been processed")
+	}
+	return nil
+}
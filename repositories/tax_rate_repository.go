@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TaxRateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTaxRateRepository(collection *mongo.Collection) *TaxRateRepository {
+	return &TaxRateRepository{collection}
+}
+
+func (r *TaxRateRepository) Create(rate models.TaxRate) (models.TaxRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, rate)
+	return rate, err
+}
+
+func (r *TaxRateRepository) FindAll() ([]models.TaxRate, error) {
+	return r.find(bson.M{})
+}
+
+func (r *TaxRateRepository) FindActive() ([]models.TaxRate, error) {
+	return r.find(bson.M{"active": true})
+}
+
+func (r *TaxRateRepository) find(filter bson.M) ([]models.TaxRate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.TaxRate{}
+	err = cursor.All(ctx, &rates)
+	return rates, err
+}
+
+func (r *TaxRateRepository) Update(id string, rate models.TaxRate) (models.TaxRate, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.TaxRate{}, errors.New("invalid tax rate id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":       rate.Name,
+			"category":   rate.Category,
+			"state":      rate.State,
+			"rate":       rate.Rate,
+			"inclusive":  rate.Inclusive,
+			"active":     rate.Active,
+			"updated_at": rate.UpdatedAt,
+		},
+	}
+
+	var updated models.TaxRate
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": oid},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return models.TaxRate{}, errors.New("tax rate not found")
+	}
+	return updated, err
+}
+
+func (r *TaxRateRepository) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid tax rate id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("tax rate not found")
+	}
+	return nil
+}
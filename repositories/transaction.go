@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"adhomes-backend/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn inside a MongoDB transaction on the application database.
+// Repository calls made with the session context passed to fn are committed together,
+// or rolled back together if fn returns an error.
+func WithTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -10,6 +10,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type WalletRepository struct {
@@ -22,6 +23,41 @@ func NewWalletRepository() *WalletRepository {
 	}
 }
 
+// EnsureIndexes gives every user at most one wallet.
+func (r *WalletRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// EnsureWallet returns the user's wallet, creating an empty one in currency if they
+// have none. An existing wallet keeps the currency it was created in.
+func (r *WalletRepository) EnsureWallet(ctx context.Context, userID string, currency string) (*models.Wallet, error) {
+	now := time.Now()
+	res := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$setOnInsert": bson.M{
+			"user_id":    userID,
+			"balance":    models.NewMoney(0, currency),
+			"created_at": now,
+			"updated_at": now,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+
+	var wallet models.Wallet
+	if err := res.Decode(&wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
 func (r *WalletRepository) FindByUserID(ctx context.Context, userID string) (*models.Wallet, error) {
 	var wallet models.Wallet
 	err := r.collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&wallet)
@@ -37,23 +73,24 @@ func (r *WalletRepository) FindByUserID(ctx context.Context, userID string) (*mo
 func (r *WalletRepository) IncreaseBalance(
 	ctx context.Context,
 	userID string,
-	amount float64,
+	amount models.Money,
 ) (*models.Wallet, error) {
 
 	update := bson.M{
-		"$inc": bson.M{"balance": amount},
+		"$inc": bson.M{"balance.amount": amount.Amount},
 		"$set": bson.M{"updated_at": time.Now()},
 	}
 
+	// A wallet is pinned to one currency; amounts in any other are refused
 	res := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"user_id": userID},
+		bson.M{"user_id": userID, "balance.currency": amount.Currency},
 		update,
 	)
 
 	if res.Err() != nil {
 		if res.Err() == mongo.ErrNoDocuments {
-			return nil, errors.New("wallet not found")
+			return nil, errors.New("wallet not found for this currency")
 		}
 		return nil, res.Err()
 	}
@@ -69,23 +106,36 @@ func (r *WalletRepository) IncreaseBalance(
 func (r *WalletRepository) DecreaseBalance(
 	ctx context.Context,
 	userID string,
-	amount float64,
+	amount models.Money,
 ) (*models.Wallet, error) {
 
 	update := bson.M{
-		"$inc": bson.M{"balance": -amount},
+		"$inc": bson.M{"balance.amount": -amount.Amount},
 		"$set": bson.M{"updated_at": time.Now()},
 	}
 
+	// A wallet is pinned to one currency; amounts in any other are refused.
+	// The balance check is part of the filter, so concurrent debits cannot overdraw it.
 	res := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"user_id": userID},
+		bson.M{
+			"user_id":          userID,
+			"balance.currency": amount.Currency,
+			"balance.amount":   bson.M{"$gte": amount.Amount},
+		},
 		update,
 	)
 
 	if res.Err() != nil {
 		if res.Err() == mongo.ErrNoDocuments {
-			return nil, errors.New("wallet not found")
+			count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "balance.currency": amount.Currency})
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, errors.New("insufficient wallet balance")
+			}
+			return nil, errors.New("wallet not found for this currency")
 		}
 		return nil, res.Err()
 	}
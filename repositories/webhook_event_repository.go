@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"adhomes-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookEventRepository(collection *mongo.Collection) *WebhookEventRepository {
+	return &WebhookEventRepository{collection}
+}
+
+// EnsureIndexes supports looking events up by payment reference and listing the latest.
+func (r *WebhookEventRepository) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "reference", Value: 1}, {Key: "received_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "received_at", Value: -1}}},
+	})
+	return err
+}
+
+func (r *WebhookEventRepository) Create(event models.WebhookEvent) (models.WebhookEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	_, err := r.collection.InsertOne(ctx, event)
+	return event, err
+}
+
+func (r *WebhookEventRepository) FindByID(id string) (models.WebhookEvent, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.WebhookEvent{}, errors.New("invalid webhook event id")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var event models.WebhookEvent
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&event)
+	if err == mongo.ErrNoDocuments {
+		return models.WebhookEvent{}, errors.New("webhook event not found")
+	}
+	return event, err
+}
+
+// FindAll lists events, newest first, optionally only those in status.
+func (r *WebhookEventRepository) FindAll(status string, limit int64) ([]models.WebhookEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "received_at", Value: -1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.WebhookEvent{}
+	err = cursor.All(ctx, &events)
+	return events, err
+}
+
+// RecordOutcome stores the result of processing an event and counts the attempt.
+func (r *WebhookEventRepository) RecordOutcome(id primitive.ObjectID, status string, message string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"status": status, "error": message, "processed_at": at},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return err
+}
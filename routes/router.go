@@ -6,11 +6,15 @@ import (
 	"adhomes-backend/middleware"
 	"adhomes-backend/repositories"
 	"adhomes-backend/services_impl"
+	"adhomes-backend/workers"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine) {
+// SetupRoutes wires repositories, services and controllers, registers the HTTP
+// routes on r and the background jobs on scheduler.
+func SetupRoutes(r *gin.Engine, scheduler *workers.Scheduler) {
 
 	// ==========================
 	// COLLECTION
@@ -20,6 +24,16 @@ func SetupRoutes(r *gin.Engine) {
 	userCollection := config.DB.Collection("users")
 	favouriteCollection := config.DB.Collection("favourites")
 	paymentCollection := config.DB.Collection("payments")
+	deliveryZoneCollection := config.DB.Collection("delivery_zones")
+	couponCollection := config.DB.Collection("coupons")
+	couponUsageCollection := config.DB.Collection("coupon_usages")
+	taxRateCollection := config.DB.Collection("tax_rates")
+	counterCollection := config.DB.Collection("counters")
+	idempotencyCollection := config.DB.Collection("idempotency_keys")
+	exchangeRateCollection := config.DB.Collection("exchange_rates")
+	returnCollection := config.DB.Collection("returns")
+	webhookEventCollection := config.DB.Collection("webhook_events")
+	reconciliationReportCollection := config.DB.Collection("reconciliation_reports")
 
 	// ==========================
 	// REPOSITORIES
@@ -27,17 +41,80 @@ func SetupRoutes(r *gin.Engine) {
 	productRepo := repositories.NewProductRepository(productCollection)
 	orderRepo := repositories.NewOrderRepository(orderCollection)
 	userRepo := repositories.NewUserRepository(userCollection)
-	favouriteRepo := repositories.NewFavoriteRepository(favouriteCollection)
+	favouriteRepo := repositories.NewFavouriteRepository(favouriteCollection)
 	paymentRepo := repositories.NewPaymentRepository(paymentCollection)
+	walletRepo := repositories.NewWalletRepository()
+	deliveryZoneRepo := repositories.NewDeliveryZoneRepository(deliveryZoneCollection)
+	couponRepo := repositories.NewCouponRepository(couponCollection, couponUsageCollection)
+	taxRateRepo := repositories.NewTaxRateRepository(taxRateCollection)
+	counterRepo := repositories.NewCounterRepository(counterCollection)
+	idempotencyRepo := repositories.NewIdempotencyRepository(idempotencyCollection)
+	exchangeRateRepo := repositories.NewExchangeRateRepository(exchangeRateCollection)
+	returnRepo := repositories.NewReturnRepository(returnCollection)
+	cartRepo := repositories.NewCartRepository()
+	webhookEventRepo := repositories.NewWebhookEventRepository(webhookEventCollection)
+	reconciliationReportRepo := repositories.NewReconciliationReportRepository(reconciliationReportCollection)
+
+	if err := orderRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create order indexes:", err)
+	}
+	if err := couponRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create coupon indexes:", err)
+	}
+	if err := idempotencyRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create idempotency key indexes:", err)
+	}
+	if err := exchangeRateRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create exchange rate indexes:", err)
+	}
+	if err := returnRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create return indexes:", err)
+	}
+	if err := paymentRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create payment indexes:", err)
+	}
+	if err := webhookEventRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create webhook event indexes:", err)
+	}
+	if err := walletRepo.EnsureIndexes(); err != nil {
+		log.Println("failed to create wallet indexes:", err)
+	}
 
 	// ==========================
 	// SERVICES
 	// ==========================
-	productService := services_impl.NewProductService(productRepo)
-	orderService := services_impl.NewOrderService(orderRepo, productRepo)
-	userService := services_impl.NewUserService(userRepo)
-	favouriteService := services_impl.NewFavoriteService(favouriteRepo)
-	paymentService := services_impl.NewPaymentService(paymentRepo)
+	paystackConfig := config.LoadPaystackConfig()
+	if paystackConfig.SecretKey == "" {
+		log.Println("PAYSTACK_SECRET_KEY is not set; card payments will fail")
+	}
+	paystackProvider := services_impl.NewPaystackProvider(paystackConfig)
+
+	productService := services_impl.NewProductService(productRepo, exchangeRateRepo)
+	exchangeRateService := services_impl.NewExchangeRateService(exchangeRateRepo)
+	deliveryZoneService := services_impl.NewDeliveryZoneService(deliveryZoneRepo, exchangeRateRepo)
+	couponService := services_impl.NewCouponService(couponRepo)
+	taxService := services_impl.NewTaxService(taxRateRepo, orderRepo)
+	orderService := services_impl.NewOrderService(orderRepo, productRepo, paymentRepo, walletRepo, couponRepo, taxRateRepo, exchangeRateRepo, deliveryZoneService, paystackProvider, config.LoadCancellationPolicy())
+	userService := services_impl.NewUserService(userRepo, walletRepo)
+	favouriteService := services_impl.NewFavouriteService(favouriteRepo)
+	walletService := services_impl.NewWalletService(walletRepo)
+	paymentService := services_impl.NewPaymentService(paymentRepo, orderRepo, walletRepo, reconciliationReportRepo, paystackProvider)
+	invoiceService := services_impl.NewInvoiceService(orderRepo, paymentRepo, counterRepo)
+	deliveryService := services_impl.NewDeliveryService()
+	checkoutService := services_impl.NewCheckoutService(orderService, paymentService, deliveryService)
+	reorderService := services_impl.NewReorderService(orderRepo, productRepo, cartRepo, exchangeRateRepo, orderService)
+	webhookService := services_impl.NewWebhookService(webhookEventRepo, paymentRepo, orderRepo, walletRepo, paystackProvider)
+	returnService := services_impl.NewReturnService(returnRepo, orderRepo, productRepo, paymentRepo, walletRepo, paystackProvider, config.LoadReturnWindow())
+
+	// ==========================
+	// BACKGROUND JOBS
+	// ==========================
+	if expiry := config.LoadOrderExpiryPolicy(); expiry.After > 0 {
+		scheduler.Every("order-expiry", expiry.Interval, workers.OrderExpiryJob(orderService, expiry.After))
+	}
+	if reconcile := config.LoadReconciliationPolicy(); reconcile.After > 0 {
+		scheduler.Every("payment-reconciliation", reconcile.Interval, workers.PaymentReconciliationJob(paymentService, reconcile.After, reconcile.HoldTimeout))
+	}
 
 	// ==========================
 	// CONTROLLERS
@@ -47,6 +124,16 @@ func SetupRoutes(r *gin.Engine) {
 	orderController := controllers.NewOrderController(orderService)
 	favouriteController := controllers.NewFavoriteController(favouriteService)
 	paymentController := controllers.NewPaymentController(paymentService)
+	deliveryZoneController := controllers.NewDeliveryZoneController(deliveryZoneService)
+	couponController := controllers.NewCouponController(couponService)
+	taxController := controllers.NewTaxController(taxService)
+	invoiceController := controllers.NewInvoiceController(invoiceService)
+	exchangeRateController := controllers.NewExchangeRateController(exchangeRateService)
+	checkoutController := controllers.NewCheckoutController(checkoutService)
+	returnController := controllers.NewReturnController(returnService)
+	reorderController := controllers.NewReorderController(reorderService)
+	webhookController := controllers.NewWebhookController(webhookService)
+	walletController := controllers.NewWalletController(walletService, paymentService)
 
 	adminController := controllers.NewAdminController(
 		productService,
@@ -67,19 +154,41 @@ func SetupRoutes(r *gin.Engine) {
 	r.GET("/products", productController.GetAllProducts)
 	r.GET("/products/:id", productController.GetProductByID)
 
+	// ==========================
+	// PUBLIC GUEST CHECKOUT ROUTES
+	// ==========================
+	r.POST("/checkout/guest", checkoutController.GuestCheckout)
+	r.GET("/track/:token", checkoutController.TrackOrder)
+
+	// ==========================
+	// PAYMENT PROVIDER WEBHOOKS (signature checked)
+	// ==========================
+	r.POST("/webhooks/paystack", webhookController.HandlePaystack)
+
 	// ==========================
 	// USER ROUTES (JWT PROTECTED)
 	// ==========================
+	idempotent := middleware.Idempotency(idempotencyRepo, config.LoadIdempotencyTTL())
+
 	userRoutes := r.Group("/user")
 	userRoutes.Use(middleware.AuthMiddleware())
 	{
 		// Orders
-		userRoutes.POST("/orders", orderController.CreateOrder)
+		userRoutes.POST("/orders", idempotent, orderController.CreateOrder)
 		userRoutes.GET("/orders/:id", orderController.GetOrderByID)
 		userRoutes.GET("/orders", orderController.GetOrdersByUserID)
 		userRoutes.DELETE("/orders/:id", orderController.DeleteOrder)
 		userRoutes.PUT("/orders/:id", orderController.UpdateOrder)
 		userRoutes.PUT("/orders/:id/status", orderController.UpdateOrderStatus)
+		userRoutes.POST("/orders/:id/cancel", orderController.CancelOrder)
+		userRoutes.GET("/orders/:id/invoice", invoiceController.GetUserInvoice)
+		userRoutes.POST("/orders/claim", orderController.ClaimGuestOrder)
+		userRoutes.POST("/orders/:id/reorder", idempotent, reorderController.Reorder)
+
+		// Returns
+		userRoutes.POST("/orders/:id/returns", returnController.CreateReturn)
+		userRoutes.GET("/returns", returnController.GetUserReturns)
+		userRoutes.GET("/returns/:id", returnController.GetUserReturn)
 
 		// Favourites
 		userRoutes.POST("/favourite", favouriteController.AddFavorite)
@@ -87,7 +196,14 @@ func SetupRoutes(r *gin.Engine) {
 		userRoutes.DELETE("/favourite/:id", favouriteController.RemoveFavorite)
 
 		// Payments
-		userRoutes.POST("/payments", paymentController.MakePayment)
+		userRoutes.POST("/payments", idempotent, paymentController.MakePayment)
+		userRoutes.GET("/payments", paymentController.GetUserPayments)
+		userRoutes.GET("/orders/:id/payments", paymentController.GetOrderPayments)
+		userRoutes.GET("/payments/:reference/verify", paymentController.VerifyPayment)
+
+		// Wallet
+		userRoutes.GET("/wallet", walletController.GetWallet)
+		userRoutes.POST("/wallet/topup", idempotent, walletController.TopUp)
 	}
 
 	// ==========================
@@ -107,6 +223,50 @@ func SetupRoutes(r *gin.Engine) {
 		admin.GET("/orders", adminController.GetAllOrders)
 		admin.PUT("/orders/:id/approve", adminController.ApproveOrder)
 		admin.PUT("/orders/:id/cancel", adminController.CancelOrder)
+		admin.PUT("/orders/:id/status", adminController.UpdateOrderStatus)
+		admin.GET("/orders/:id/invoice", invoiceController.GetInvoice)
+
+		// Returns
+		admin.GET("/returns", returnController.GetReturns)
+		admin.GET("/returns/:id", returnController.GetReturn)
+		admin.PUT("/returns/:id/approve", returnController.ApproveReturn)
+		admin.PUT("/returns/:id/reject", returnController.RejectReturn)
+		admin.PUT("/returns/:id/receive", returnController.ReceiveReturn)
+
+		// Payments
+		admin.GET("/payments", paymentController.GetAllPayments)
+		admin.GET("/payments/reconciliation", paymentController.GetReconciliationReports)
+		admin.POST("/payments/:id/refund", idempotent, paymentController.RefundPayment)
+
+		// Payment webhooks
+		admin.GET("/webhooks", webhookController.GetEvents)
+		admin.POST("/webhooks/:id/replay", webhookController.ReplayEvent)
+
+		// Delivery Zones
+		admin.POST("/delivery-zones", deliveryZoneController.CreateZone)
+		admin.GET("/delivery-zones", deliveryZoneController.GetZones)
+		admin.PUT("/delivery-zones/:id", deliveryZoneController.UpdateZone)
+		admin.DELETE("/delivery-zones/:id", deliveryZoneController.DeleteZone)
+
+		// Coupons
+		admin.POST("/coupons", couponController.CreateCoupon)
+		admin.GET("/coupons", couponController.GetCoupons)
+		admin.GET("/coupons/:id", couponController.GetCouponByID)
+		admin.PUT("/coupons/:id", couponController.UpdateCoupon)
+		admin.DELETE("/coupons/:id", couponController.DeleteCoupon)
+
+		// Tax
+		admin.POST("/tax-rates", taxController.CreateTaxRate)
+		admin.GET("/tax-rates", taxController.GetTaxRates)
+		admin.PUT("/tax-rates/:id", taxController.UpdateTaxRate)
+		admin.DELETE("/tax-rates/:id", taxController.DeleteTaxRate)
+		admin.GET("/reports/tax", taxController.TaxReport)
+
+		// Currencies
+		admin.POST("/exchange-rates", exchangeRateController.CreateRate)
+		admin.GET("/exchange-rates", exchangeRateController.GetRates)
+		admin.PUT("/exchange-rates/:id", exchangeRateController.UpdateRate)
+		admin.DELETE("/exchange-rates/:id", exchangeRateController.DeleteRate)
 
 		// User Management
 		admin.GET("/users", adminController.GetAllUsers)
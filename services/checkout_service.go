@@ -0,0 +1,8 @@
+package services
+
+import "adhomes-backend/models"
+
+type CheckoutService interface {
+	GuestCheckout(order models.Order) (models.GuestCheckout, error)
+	TrackOrder(token string) (models.OrderTracking, error)
+}
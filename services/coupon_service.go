@@ -0,0 +1,11 @@
+package services
+
+import "adhomes-backend/models"
+
+type CouponService interface {
+	CreateCoupon(coupon models.Coupon) (models.Coupon, error)
+	GetCoupons() ([]models.Coupon, error)
+	GetCouponByID(id string) (models.Coupon, error)
+	UpdateCoupon(id string, coupon models.Coupon) (models.Coupon, error)
+	DeleteCoupon(id string) error
+}
@@ -0,0 +1,14 @@
+package services
+
+import "adhomes-backend/models"
+
+type DeliveryZoneService interface {
+	CreateZone(zone models.DeliveryZone) (models.DeliveryZone, error)
+	GetZones() ([]models.DeliveryZone, error)
+	UpdateZone(id string, zone models.DeliveryZone) (models.DeliveryZone, error)
+	DeleteZone(id string) error
+
+	// QuoteDeliveryFee prices delivery of an order to address.
+	// It fails when no active zone covers the address.
+	QuoteDeliveryFee(deliveryType string, address models.ShippingAddress, subtotal models.Money, weightKg float64) (models.Money, error)
+}
@@ -0,0 +1,10 @@
+package services
+
+import "adhomes-backend/models"
+
+type ExchangeRateService interface {
+	CreateRate(rate models.ExchangeRate) (models.ExchangeRate, error)
+	GetRates() ([]models.ExchangeRate, error)
+	UpdateRate(id string, rate models.ExchangeRate) (models.ExchangeRate, error)
+	DeleteRate(id string) error
+}
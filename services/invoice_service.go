@@ -0,0 +1,8 @@
+package services
+
+type InvoiceService interface {
+	// GetUserInvoice renders the PDF invoice for one of userID's orders and returns it with its invoice number.
+	GetUserInvoice(orderID, userID string) ([]byte, string, error)
+	// GetInvoice renders the PDF invoice for any order (admin).
+	GetInvoice(orderID string) ([]byte, string, error)
+}
@@ -1,18 +1,36 @@
 package services
 
-import "adhomes-backend/models"
+import (
+	"context"
+	"time"
+
+	"adhomes-backend/models"
+)
 
 type OrderService interface {
 	CreateOrder(order models.Order) (models.Order, error)
-	GetOrderByID(id string) (models.Order, error)
+	GetAllOrders(query models.OrderListQuery) ([]models.Order, models.Pagination, error)
+
+	// Customer actions, scoped to orders owned by userID
+	GetOrderByID(id string, userID string) (models.Order, error)
 	GetOrdersByUserID(userID string) ([]models.Order, error)
-	GetAllOrders() ([]models.Order, error)
+	UpdateOrder(id string, userID string, order models.Order) (models.Order, error)
+	UpdateOrderStatus(id string, userID string, status string) error
+	DeleteOrder(id string, userID string) error
+	CancelMyOrder(id string, userID string, reason string) (models.Order, error)
 
-	UpdateOrder(id string, order models.Order) (models.Order, error)
-	UpdateOrderStatus(id string, status string) error
+	// Guest checkout. Guest orders are reached through their tracking token until
+	// the customer claims them into an account registered with the same email.
+	CreateGuestOrder(order models.Order) (models.Order, string, error)
+	GetOrderByTrackingToken(token string) (models.Order, error)
+	ClaimGuestOrder(token string, userID string) (models.Order, error)
 
-	ApproveOrder(id string) error
-	CancelOrder(id string) error
+	// Admin actions
+	ApproveOrder(id string, actor string) error
+	UpdateFulfilmentStatus(id string, actor string, status string) error
+	CancelOrder(id string, actor string, reason string) error
 
-	DeleteOrder(id string) error
+	// ExpireUnpaidOrders cancels orders that have stayed unpaid for longer than olderThan
+	// and reports how many it cancelled.
+	ExpireUnpaidOrders(ctx context.Context, olderThan time.Duration) (int, error)
 }
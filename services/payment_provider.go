@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"adhomes-backend/models"
+)
+
+// PaymentProvider is a card payment gateway.
+type PaymentProvider interface {
+	// InitializeTransaction opens a transaction the customer completes on the
+	// provider's checkout page.
+	InitializeTransaction(ctx context.Context, req models.TransactionRequest) (models.TransactionInit, error)
+	// VerifyTransaction asks the provider what became of the transaction with reference.
+	VerifyTransaction(ctx context.Context, reference string) (models.TransactionStatus, error)
+	// Refund asks the provider to return part or all of a successful transaction.
+	// The provider confirms the refund later through a webhook.
+	Refund(ctx context.Context, req models.RefundRequest) error
+
+	// VerifyWebhook reports whether signature proves body came from the provider.
+	VerifyWebhook(body []byte, signature string) bool
+	// ParseWebhook reads a verified callback body.
+	ParseWebhook(body []byte) (models.PaymentEvent, error)
+}
+
+// ProviderError is returned when a payment provider cannot be reached, times out
+// or refuses a request. It is never the customer's fault.
+type ProviderError struct {
+	Provider string
+	Message  string
+	Timeout  bool
+}
+
+func (e *ProviderError) Error() string {
+	if e.Timeout {
+		return fmt.Sprintf("%s did not respond in time", e.Provider)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+}
@@ -1,9 +1,33 @@
 package services
 
-import "adhomes-backend/models"
+import (
+	"context"
+	"time"
+
+	"adhomes-backend/models"
+)
 
 type PaymentService interface {
 	MakePayment(req models.PaymentRequest) (models.Payment, string, error)
+	StartGuestPayment(order models.Order) (models.Payment, string, error)
+	StartWalletTopUp(userID string, amount models.Money) (models.Payment, string, error)
+
+	// History. GetUserPayments and GetOrderPayments only return userID's own payments.
+	GetUserPayments(userID string, query models.PaymentListQuery) ([]models.Payment, models.Pagination, error)
+	GetOrderPayments(orderID string, userID string) ([]models.Payment, error)
+	GetAllPayments(query models.PaymentListQuery) ([]models.Payment, models.Pagination, []models.PaymentTotal, error)
+
+	// RefundPayment returns part or all of a successful charge, to the wallet for wallet
+	// charges and through the provider for card charges, and returns the refund record.
+	RefundPayment(id string, req models.RefundPaymentRequest, actor string) (models.Payment, error)
+
+	// Verification asks the provider for the outcome of a payment and records it.
+	VerifyPayment(reference string) (models.Payment, error)
+	VerifyUserPayment(reference string, userID string) (models.Payment, error)
 
-	// VerifyPayment(reference string) error
+	// ReconcilePendingPayments re-verifies card payments pending for longer than olderThan.
+	// Split payments whose card part is still undecided after holdTimeout have their
+	// wallet part released.
+	ReconcilePendingPayments(ctx context.Context, olderThan time.Duration, holdTimeout time.Duration) (models.ReconciliationReport, error)
+	GetReconciliationReports() ([]models.ReconciliationReport, error)
 }
@@ -8,4 +8,8 @@ type ProductService interface {
 	DeleteProduct(id string) error
 	GetAllProducts() ([]models.Product, error)
 	GetProductByID(id string) (*models.Product, error)
+
+	// GetAllProductsIn and GetProductByIDIn price products in currency instead of the base currency.
+	GetAllProductsIn(currency string) ([]models.Product, error)
+	GetProductByIDIn(id string, currency string) (*models.Product, error)
 }
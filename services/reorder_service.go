@@ -0,0 +1,9 @@
+package services
+
+import "adhomes-backend/models"
+
+type ReorderService interface {
+	// Reorder places the items of one of userID's past orders again, as a new
+	// order or into their cart depending on mode.
+	Reorder(id string, userID string, mode string) (models.ReorderResult, error)
+}
@@ -0,0 +1,17 @@
+package services
+
+import "adhomes-backend/models"
+
+type ReturnService interface {
+	// Customer actions, scoped to returns owned by userID
+	CreateReturn(ret models.Return) (models.Return, error)
+	GetUserReturns(userID string) ([]models.Return, error)
+	GetUserReturn(id string, userID string) (models.Return, error)
+
+	// Admin actions
+	GetReturns(status string) ([]models.Return, error)
+	GetReturn(id string) (models.Return, error)
+	ApproveReturn(id string, actor string, note string) (models.Return, error)
+	RejectReturn(id string, actor string, reason string) (models.Return, error)
+	ReceiveReturn(id string, actor string) (models.Return, error)
+}
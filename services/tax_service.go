@@ -0,0 +1,13 @@
+package services
+
+import "adhomes-backend/models"
+
+type TaxService interface {
+	CreateTaxRate(rate models.TaxRate) (models.TaxRate, error)
+	GetTaxRates() ([]models.TaxRate, error)
+	UpdateTaxRate(id string, rate models.TaxRate) (models.TaxRate, error)
+	DeleteTaxRate(id string) error
+
+	// TaxReport summarises tax on orders placed between from and to (inclusive dates, either may be empty).
+	TaxReport(from, to string) ([]models.TaxReportRow, error)
+}
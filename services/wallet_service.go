@@ -6,7 +6,8 @@ import (
 )
 
 type WalletService interface {
+	// GetWalletByUserID returns the user's wallet, creating an empty one on first access.
 	GetWalletByUserID(ctx context.Context, userID string) (*models.Wallet, error)
-	IncreaseBalance(ctx context.Context, userID string, amount float64) (*models.Wallet, error)
-	DecreaseBalance(ctx context.Context, userID string, amount float64) (*models.Wallet, error)
+	IncreaseBalance(ctx context.Context, userID string, amount models.Money) (*models.Wallet, error)
+	DecreaseBalance(ctx context.Context, userID string, amount models.Money) (*models.Wallet, error)
 }
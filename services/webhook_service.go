@@ -0,0 +1,12 @@
+package services
+
+import "adhomes-backend/models"
+
+type WebhookService interface {
+	// HandlePaystack verifies, logs and applies a Paystack callback.
+	HandlePaystack(body []byte, signature string) error
+
+	// Admin actions
+	GetEvents(status string) ([]models.WebhookEvent, error)
+	ReplayEvent(id string) (models.WebhookEvent, error)
+}
@@ -0,0 +1,106 @@
+package services_impl
+
+import (
+	"fmt"
+	"log"
+
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+	"adhomes-backend/utils"
+)
+
+type checkoutServiceImpl struct {
+	orderService    services.OrderService
+	paymentService  services.PaymentService
+	deliveryService services.DeliveryService
+}
+
+func NewCheckoutService(
+	orderService services.OrderService,
+	paymentService services.PaymentService,
+	deliveryService services.DeliveryService,
+) *checkoutServiceImpl {
+	return &checkoutServiceImpl{
+		orderService:    orderService,
+		paymentService:  paymentService,
+		deliveryService: deliveryService,
+	}
+}
+
+// -----------------------------
+// GUEST CHECKOUT
+// -----------------------------
+
+// GuestCheckout places a guest order and opens its Paystack payment. If the payment
+// cannot be opened the order is cancelled again, so its stock is not held for nothing.
+func (s *checkoutServiceImpl) GuestCheckout(order models.Order) (models.GuestCheckout, error) {
+	created, token, err := s.orderService.CreateGuestOrder(order)
+	if err != nil {
+		return models.GuestCheckout{}, err
+	}
+
+	payment, paymentURL, err := s.paymentService.StartGuestPayment(created)
+	if err != nil {
+		if cancelErr := s.orderService.CancelOrder(created.ID.Hex(), "system", "payment could not be started"); cancelErr != nil {
+			log.Printf("failed to cancel guest order %s: %v", created.ID.Hex(), cancelErr)
+		}
+		return models.GuestCheckout{}, fmt.Errorf("payment could not be started: %w", err)
+	}
+
+	return models.GuestCheckout{
+		Order:         created,
+		TrackingToken: token,
+		Payment:       payment,
+		PaymentURL:    paymentURL,
+	}, nil
+}
+
+// -----------------------------
+// TRACKING
+// -----------------------------
+func (s *checkoutServiceImpl) TrackOrder(token string) (models.OrderTracking, error) {
+	order, err := s.orderService.GetOrderByTrackingToken(token)
+	if err != nil {
+		return models.OrderTracking{}, err
+	}
+
+	// Most orders have no delivery record until a rider is assigned
+	var delivery *models.Delivery
+	if found, err := s.deliveryService.GetDeliveryByOrder(order.ID.Hex()); err == nil {
+		delivery = found
+	} else if err.Error() != "delivery not found" {
+		return models.OrderTracking{}, err
+	}
+
+	return trackingView(order, delivery), nil
+}
+
+// trackingView reduces an order to what the public tracking page may show.
+func trackingView(order models.Order, delivery *models.Delivery) models.OrderTracking {
+	tracking := models.OrderTracking{
+		OrderID:       order.ID.Hex(),
+		Status:        utils.NormalizeStatus(order.Status),
+		PaymentStatus: order.PaymentStatus,
+		DeliveryType:  order.DeliveryType,
+		Items:         make([]models.TrackedItem, 0, len(order.Items)),
+		TotalAmount:   order.TotalAmount,
+		History:       make([]models.TrackingEvent, 0, len(order.StatusHistory)),
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
+	}
+
+	if delivery != nil {
+		tracking.DeliveryStatus = delivery.Status
+	}
+
+	for _, item := range order.Items {
+		tracking.Items = append(tracking.Items, models.TrackedItem{Name: item.Name, Quantity: item.Quantity})
+	}
+
+	// Who made each change and why stays internal
+	for _, change := range order.StatusHistory {
+		tracking.History = append(tracking.History, models.TrackingEvent{Status: change.To, At: change.ChangedAt})
+	}
+
+	return tracking
+}
@@ -0,0 +1,60 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTrackingTokenStoresOnlyItsHash(t *testing.T) {
+	token, hash, err := newTrackingToken()
+	assert.NoError(t, err)
+	assert.Len(t, token, 2*trackingTokenBytes)
+	assert.NotEqual(t, token, hash)
+	assert.Equal(t, hash, hashTrackingToken(token))
+
+	other, _, err := newTrackingToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestCouponHolderCountsGuestsByEmail(t *testing.T) {
+	assert.Equal(t, "ada@example.com", couponHolder(models.Order{UserID: "ada@example.com"}))
+
+	guest := models.Order{Guest: true, CustomerEmail: "Ada@Example.com"}
+	assert.Equal(t, "guest:ada@example.com", couponHolder(guest))
+
+	// Claiming the order must not move the redemption
+	guest.UserID = "ada@example.com"
+	assert.Equal(t, "guest:ada@example.com", couponHolder(guest))
+}
+
+func TestTrackingViewHidesPrivateDetails(t *testing.T) {
+	placed := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	order := models.Order{
+		CustomerName:    "Ada",
+		CustomerPhone:   "08030000000",
+		ShippingAddress: models.ShippingAddress{Street: "1 Marina", State: "Lagos"},
+		DeliveryType:    models.DeliveryTypeDelivery,
+		Items:           []models.OrderItem{{Name: "Chair", Quantity: 2, UnitPrice: ngn(500000)}},
+		TotalAmount:     ngn(1000000),
+		Status:          "Pending",
+		PaymentStatus:   "unpaid",
+		StatusHistory: []models.OrderStatusChange{
+			{To: "pending", Actor: "guest", Reason: "order created", ChangedAt: placed},
+		},
+	}
+
+	tracking := trackingView(order, &models.Delivery{Status: "Assigned"})
+
+	assert.Equal(t, "pending", tracking.Status)
+	assert.Equal(t, "Assigned", tracking.DeliveryStatus)
+	assert.Equal(t, []models.TrackedItem{{Name: "Chair", Quantity: 2}}, tracking.Items)
+	assert.Equal(t, []models.TrackingEvent{{Status: "pending", At: placed}}, tracking.History)
+	assert.Equal(t, ngn(1000000), tracking.TotalAmount)
+
+	assert.Empty(t, trackingView(order, nil).DeliveryStatus)
+}
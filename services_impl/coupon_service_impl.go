@@ -0,0 +1,141 @@
+package services_impl
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type couponServiceImpl struct {
+	couponRepo *repositories.CouponRepository
+}
+
+func NewCouponService(couponRepo *repositories.CouponRepository) *couponServiceImpl {
+	return &couponServiceImpl{
+		couponRepo: couponRepo,
+	}
+}
+
+// -----------------------------
+// ADMIN CRUD
+// -----------------------------
+func (s *couponServiceImpl) CreateCoupon(coupon models.Coupon) (models.Coupon, error) {
+	if err := validateCoupon(&coupon); err != nil {
+		return models.Coupon{}, err
+	}
+
+	coupon.ID = primitive.NewObjectID()
+	coupon.UsedCount = 0
+	coupon.CreatedAt = time.Now()
+	coupon.UpdatedAt = time.Now()
+	return s.couponRepo.Create(coupon)
+}
+
+func (s *couponServiceImpl) GetCoupons() ([]models.Coupon, error) {
+	return s.couponRepo.FindAll()
+}
+
+func (s *couponServiceImpl) GetCouponByID(id string) (models.Coupon, error) {
+	return s.couponRepo.FindByID(id)
+}
+
+func (s *couponServiceImpl) UpdateCoupon(id string, coupon models.Coupon) (models.Coupon, error) {
+	if err := validateCoupon(&coupon); err != nil {
+		return models.Coupon{}, err
+	}
+
+	coupon.UpdatedAt = time.Now()
+	return s.couponRepo.Update(id, coupon)
+}
+
+func (s *couponServiceImpl) DeleteCoupon(id string) error {
+	return s.couponRepo.Delete(id)
+}
+
+func validateCoupon(coupon *models.Coupon) error {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	if coupon.Code == "" {
+		return errors.New("coupon code is required")
+	}
+	switch coupon.Type {
+	case models.CouponPercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("percentage must be between 0 and 100")
+		}
+		coupon.Amount = models.Money{}
+	case models.CouponFixed:
+		if coupon.Amount.Amount <= 0 {
+			return errors.New("fixed coupons need an amount greater than zero")
+		}
+		coupon.Value = 0
+	}
+	if coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 || coupon.MinOrderValue.IsNegative() {
+		return errors.New("limits cannot be negative")
+	}
+	if coupon.StartsAt != nil && coupon.ExpiresAt != nil && coupon.ExpiresAt.Before(*coupon.StartsAt) {
+		return errors.New("coupon expires before it starts")
+	}
+	return nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// -----------------------------
+// DISCOUNT
+// -----------------------------
+
+// couponDiscount works out how much coupon takes off an order made of items.
+// Limits on the number of redemptions are enforced separately when the coupon is redeemed.
+func couponDiscount(coupon models.Coupon, items []models.OrderItem, subtotal models.Money, now time.Time) (models.Money, error) {
+	if !coupon.Active {
+		return models.Money{}, errors.New("coupon is not active")
+	}
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return models.Money{}, errors.New("coupon is not yet valid")
+	}
+	if coupon.ExpiresAt != nil && now.After(*coupon.ExpiresAt) {
+		return models.Money{}, errors.New("coupon has expired")
+	}
+	if subtotal.Cmp(coupon.MinOrderValue) < 0 {
+		return models.Money{}, errors.New("order does not meet the coupon's minimum value")
+	}
+
+	eligible := models.NewMoney(0, subtotal.Currency)
+	for _, item := range items {
+		if couponAppliesTo(coupon, item) {
+			eligible = eligible.Add(item.Subtotal)
+		}
+	}
+	if eligible.IsZero() {
+		return models.Money{}, errors.New("coupon does not apply to any item in this order")
+	}
+
+	if coupon.Type == models.CouponPercentage {
+		return eligible.Percent(coupon.Value), nil
+	}
+	return coupon.Amount.Min(eligible), nil
+}
+
+func couponAppliesTo(coupon models.Coupon, item models.OrderItem) bool {
+	if len(coupon.Categories) == 0 && len(coupon.ProductIDs) == 0 {
+		return true
+	}
+	for _, category := range coupon.Categories {
+		if strings.EqualFold(category, item.Category) {
+			return true
+		}
+	}
+	for _, productID := range coupon.ProductIDs {
+		if productID == item.ProductID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// ngn is a shorthand for naira amounts in tests; kobo are minor units.
+func ngn(kobo int64) models.Money {
+	return models.NewMoney(kobo, "NGN")
+}
+
+func TestCouponDiscountOnlyCountsEligibleLines(t *testing.T) {
+	coupon := models.Coupon{Type: models.CouponPercentage, Value: 10, Categories: []string{"kitchen"}, Active: true}
+	items := []models.OrderItem{
+		{ProductID: "a", Category: "Kitchen", Subtotal: ngn(500000)},
+		{ProductID: "b", Category: "bedroom", Subtotal: ngn(300000)},
+	}
+
+	discount, err := couponDiscount(coupon, items, ngn(800000), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(50000), discount)
+}
+
+func TestCouponDiscountRoundsPercentagesToTheNearestKobo(t *testing.T) {
+	coupon := models.Coupon{Type: models.CouponPercentage, Value: 12.5, Active: true}
+	items := []models.OrderItem{{ProductID: "a", Subtotal: ngn(1005)}}
+
+	discount, err := couponDiscount(coupon, items, ngn(1005), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(126), discount) // 125.625 kobo
+}
+
+func TestCouponDiscountCapsFixedAmountAtEligibleTotal(t *testing.T) {
+	coupon := models.Coupon{Type: models.CouponFixed, Amount: ngn(200000), ProductIDs: []string{"a"}, Active: true}
+	items := []models.OrderItem{{ProductID: "a", Subtotal: ngn(150000)}, {ProductID: "b", Subtotal: ngn(400000)}}
+
+	discount, err := couponDiscount(coupon, items, ngn(550000), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(150000), discount)
+}
+
+func TestCouponDiscountRejectsInvalidCoupons(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	items := []models.OrderItem{{ProductID: "a", Subtotal: ngn(100000)}}
+
+	_, err := couponDiscount(models.Coupon{Type: models.CouponFixed, Amount: ngn(10000), Active: true, ExpiresAt: &past}, items, ngn(100000), time.Now())
+	assert.EqualError(t, err, "coupon has expired")
+
+	_, err = couponDiscount(models.Coupon{Type: models.CouponFixed, Amount: ngn(10000), Active: true, MinOrderValue: ngn(500000)}, items, ngn(100000), time.Now())
+	assert.EqualError(t, err, "order does not meet the coupon's minimum value")
+}
+
+func TestUpdateCouponChangesTheFixedAmount(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		service := NewCouponService(repositories.NewCouponRepository(mt.DB.Collection("coupons"), mt.DB.Collection("coupon_usages")))
+		id := primitive.NewObjectID()
+		coupon := models.Coupon{Code: "save5k", Type: models.CouponFixed, Amount: ngn(500000), Active: true}
+
+		updated := coupon
+		updated.ID = id
+		updated.Code = "SAVE5K"
+		mt.AddMockResponses(findAndModifyReply(mockDoc(t, updated)))
+
+		got, err := service.UpdateCoupon(id.Hex(), coupon)
+		require.NoError(t, err)
+		assert.Equal(t, ngn(500000), got.Amount)
+
+		set := sentCommand(t, mt, "findAndModify", 0).Command.Lookup("update", "$set").Document()
+		assert.Equal(t, int64(500000), set.Lookup("amount", "amount").Int64())
+		assert.Equal(t, "NGN", set.Lookup("amount", "currency").StringValue())
+	})
+}
@@ -0,0 +1,167 @@
+package services_impl
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type deliveryZoneServiceImpl struct {
+	zoneRepo *repositories.DeliveryZoneRepository
+	rateRepo *repositories.ExchangeRateRepository
+}
+
+func NewDeliveryZoneService(zoneRepo *repositories.DeliveryZoneRepository, rateRepo *repositories.ExchangeRateRepository) *deliveryZoneServiceImpl {
+	return &deliveryZoneServiceImpl{
+		zoneRepo: zoneRepo,
+		rateRepo: rateRepo,
+	}
+}
+
+// -----------------------------
+// ADMIN CRUD
+// -----------------------------
+func (s *deliveryZoneServiceImpl) CreateZone(zone models.DeliveryZone) (models.DeliveryZone, error) {
+	if err := validateZone(zone); err != nil {
+		return models.DeliveryZone{}, err
+	}
+
+	zone.ID = primitive.NewObjectID()
+	zone.CreatedAt = time.Now()
+	zone.UpdatedAt = time.Now()
+	return s.zoneRepo.Create(zone)
+}
+
+func (s *deliveryZoneServiceImpl) GetZones() ([]models.DeliveryZone, error) {
+	return s.zoneRepo.FindAll()
+}
+
+func (s *deliveryZoneServiceImpl) UpdateZone(id string, zone models.DeliveryZone) (models.DeliveryZone, error) {
+	if err := validateZone(zone); err != nil {
+		return models.DeliveryZone{}, err
+	}
+
+	zone.UpdatedAt = time.Now()
+	return s.zoneRepo.Update(id, zone)
+}
+
+func (s *deliveryZoneServiceImpl) DeleteZone(id string) error {
+	return s.zoneRepo.Delete(id)
+}
+
+func validateZone(zone models.DeliveryZone) error {
+	switch zone.FeeType {
+	case models.DeliveryFeeFlat, models.DeliveryFeeWeight, models.DeliveryFeeFreeOver:
+	default:
+		return errors.New("fee_type must be flat, weight or free_over")
+	}
+	if zone.FlatFee.IsNegative() || zone.BaseFee.IsNegative() || zone.PerKgFee.IsNegative() || zone.FreeOverAmount.IsNegative() {
+		return errors.New("fees cannot be negative")
+	}
+	if zone.FeeType == models.DeliveryFeeFreeOver && zone.FreeOverAmount.IsZero() {
+		return errors.New("free_over zones need a free_over_amount")
+	}
+	return nil
+}
+
+// -----------------------------
+// FEE QUOTE
+// -----------------------------
+func (s *deliveryZoneServiceImpl) QuoteDeliveryFee(
+	deliveryType string,
+	address models.ShippingAddress,
+	subtotal models.Money,
+	weightKg float64,
+) (models.Money, error) {
+
+	free := models.NewMoney(0, subtotal.Currency)
+
+	switch deliveryType {
+	case models.DeliveryTypePickup:
+		return free, nil
+	case models.DeliveryTypeDelivery:
+	default:
+		return models.Money{}, errors.New("invalid delivery type")
+	}
+
+	zones, err := s.zoneRepo.FindActive()
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	zone := matchZone(zones, address)
+	if zone == nil {
+		return models.Money{}, errors.New("we do not deliver to this address")
+	}
+
+	// Zone fees are set in the base currency; quote in the order's.
+	rates, err := loadExchangeTable(s.rateRepo)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	switch zone.FeeType {
+	case models.DeliveryFeeWeight:
+		return rates.convert(zone.BaseFee.Add(zone.PerKgFee.Mul(int64(math.Ceil(weightKg)))), subtotal.Currency)
+	case models.DeliveryFeeFreeOver:
+		threshold, err := rates.convert(zone.FreeOverAmount, subtotal.Currency)
+		if err != nil {
+			return models.Money{}, err
+		}
+		if subtotal.Cmp(threshold) >= 0 {
+			return free, nil
+		}
+		return rates.convert(zone.FlatFee, subtotal.Currency)
+	default:
+		return rates.convert(zone.FlatFee, subtotal.Currency)
+	}
+}
+
+// matchZone picks the most specific zone covering address: a postal prefix beats a city,
+// a city beats a state and a state beats a country.
+func matchZone(zones []models.DeliveryZone, address models.ShippingAddress) *models.DeliveryZone {
+	var best *models.DeliveryZone
+	bestScore := -1
+
+	for i, zone := range zones {
+		score := 0
+
+		if zone.Country != "" {
+			if !strings.EqualFold(zone.Country, strings.TrimSpace(address.Country)) {
+				continue
+			}
+			score += 1
+		}
+		if zone.State != "" {
+			if !strings.EqualFold(zone.State, strings.TrimSpace(address.State)) {
+				continue
+			}
+			score += 2
+		}
+		if zone.City != "" {
+			if !strings.EqualFold(zone.City, strings.TrimSpace(address.City)) {
+				continue
+			}
+			score += 4
+		}
+		if zone.PostalPrefix != "" {
+			postal := strings.ToUpper(strings.ReplaceAll(address.PostalCode, " ", ""))
+			if !strings.HasPrefix(postal, strings.ToUpper(zone.PostalPrefix)) {
+				continue
+			}
+			score += 8
+		}
+
+		if score > bestScore {
+			best, bestScore = &zones[i], score
+		}
+	}
+
+	return best
+}
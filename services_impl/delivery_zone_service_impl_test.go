@@ -0,0 +1,39 @@
+package services_impl
+
+import (
+	"testing"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchZonePrefersMostSpecificZone(t *testing.T) {
+	zones := []models.DeliveryZone{
+		{Name: "Nigeria", Country: "Nigeria"},
+		{Name: "Lagos", Country: "Nigeria", State: "Lagos"},
+		{Name: "Lekki", Country: "Nigeria", State: "Lagos", PostalPrefix: "1052"},
+	}
+
+	zone := matchZone(zones, models.ShippingAddress{Country: "nigeria", State: "Lagos", PostalCode: "105 201"})
+	assert.Equal(t, "Lekki", zone.Name)
+
+	zone = matchZone(zones, models.ShippingAddress{Country: "Nigeria", State: "Lagos", PostalCode: "100001"})
+	assert.Equal(t, "Lagos", zone.Name)
+
+	zone = matchZone(zones, models.ShippingAddress{Country: "Nigeria", State: "Kano"})
+	assert.Equal(t, "Nigeria", zone.Name)
+}
+
+func TestMatchZoneReturnsNilForUnservedAddress(t *testing.T) {
+	zones := []models.DeliveryZone{{Name: "Lagos", Country: "Nigeria", State: "Lagos"}}
+
+	assert.Nil(t, matchZone(zones, models.ShippingAddress{Country: "Ghana", State: "Accra"}))
+}
+
+func TestValidateZoneRejectsUnknownFeeTypes(t *testing.T) {
+	for _, feeType := range []string{"", "distance", "Flat"} {
+		assert.EqualError(t, validateZone(models.DeliveryZone{FeeType: feeType}), "fee_type must be flat, weight or free_over", feeType)
+	}
+	assert.NoError(t, validateZone(models.DeliveryZone{FeeType: models.DeliveryFeeWeight}))
+}
@@ -0,0 +1,125 @@
+package services_impl
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exchangeRateServiceImpl struct {
+	rateRepo *repositories.ExchangeRateRepository
+}
+
+func NewExchangeRateService(rateRepo *repositories.ExchangeRateRepository) *exchangeRateServiceImpl {
+	return &exchangeRateServiceImpl{
+		rateRepo: rateRepo,
+	}
+}
+
+// -----------------------------
+// ADMIN CRUD
+// -----------------------------
+func (s *exchangeRateServiceImpl) CreateRate(rate models.ExchangeRate) (models.ExchangeRate, error) {
+	if err := validateExchangeRate(&rate); err != nil {
+		return models.ExchangeRate{}, err
+	}
+
+	rate.ID = primitive.NewObjectID()
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = time.Now()
+	return s.rateRepo.Create(rate)
+}
+
+func (s *exchangeRateServiceImpl) GetRates() ([]models.ExchangeRate, error) {
+	return s.rateRepo.FindAll()
+}
+
+func (s *exchangeRateServiceImpl) UpdateRate(id string, rate models.ExchangeRate) (models.ExchangeRate, error) {
+	if err := validateExchangeRate(&rate); err != nil {
+		return models.ExchangeRate{}, err
+	}
+
+	rate.UpdatedAt = time.Now()
+	return s.rateRepo.Update(id, rate)
+}
+
+func (s *exchangeRateServiceImpl) DeleteRate(id string) error {
+	return s.rateRepo.Delete(id)
+}
+
+func validateExchangeRate(rate *models.ExchangeRate) error {
+	rate.Currency = strings.ToUpper(strings.TrimSpace(rate.Currency))
+	if rate.Currency == models.DefaultCurrency {
+		return errors.New("the base currency does not need an exchange rate")
+	}
+	if rate.Rate <= 0 {
+		return errors.New("rate must be greater than zero")
+	}
+	return nil
+}
+
+// -----------------------------
+// CONVERSION
+// -----------------------------
+
+// exchangeTable holds the active rates for one pricing operation, so a single
+// order or product listing is converted with consistent rates.
+type exchangeTable map[string]float64
+
+func loadExchangeTable(rateRepo *repositories.ExchangeRateRepository) (exchangeTable, error) {
+	rates, err := rateRepo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+
+	table := exchangeTable{models.DefaultCurrency: 1}
+	for _, rate := range rates {
+		table[rate.Currency] = rate.Rate
+	}
+	return table, nil
+}
+
+// resolve normalises currency, defaulting to the base currency, and checks that we sell in it.
+func (t exchangeTable) resolve(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if _, ok := t[currency]; !ok {
+		return "", errors.New("unsupported currency")
+	}
+	return currency, nil
+}
+
+// convert expresses amount in currency, going through the base currency when neither side is it.
+func (t exchangeTable) convert(amount models.Money, currency string) (models.Money, error) {
+	if amount.Currency == currency {
+		return amount, nil
+	}
+
+	from, ok := t[amount.Currency]
+	if !ok {
+		return models.Money{}, errors.New("unsupported currency")
+	}
+	to, ok := t[currency]
+	if !ok {
+		return models.Money{}, errors.New("unsupported currency")
+	}
+
+	return amount.Convert(currency, to/from), nil
+}
+
+// productPrice is the product's explicit price in currency if it has one, else its converted base price.
+func (t exchangeTable) productPrice(product models.Product, currency string) (models.Money, error) {
+	for _, price := range product.Prices {
+		if price.Currency == currency {
+			return price, nil
+		}
+	}
+	return t.convert(product.Price, currency)
+}
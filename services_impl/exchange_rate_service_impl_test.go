@@ -0,0 +1,59 @@
+package services_impl
+
+import (
+	"testing"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeTableConvertsThroughBaseCurrency(t *testing.T) {
+	rates := exchangeTable{"NGN": 1, "USD": 1500, "GBP": 2000}
+
+	usd, err := rates.convert(ngn(300000), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(200, "USD"), usd)
+
+	gbp, err := rates.convert(models.NewMoney(400, "USD"), "GBP")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(300, "GBP"), gbp)
+
+	_, err = rates.convert(ngn(100), "EUR")
+	assert.EqualError(t, err, "unsupported currency")
+}
+
+func TestExchangeTablePrefersPriceOverrides(t *testing.T) {
+	rates := exchangeTable{"NGN": 1, "USD": 1500}
+	product := models.Product{Price: ngn(1500000), Prices: []models.Money{models.NewMoney(999, "USD")}}
+
+	price, err := rates.productPrice(product, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(999, "USD"), price)
+
+	price, err = rates.productPrice(product, "NGN")
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(1500000), price)
+}
+
+func TestExchangeTableResolveDefaultsToBase(t *testing.T) {
+	rates := exchangeTable{"NGN": 1}
+
+	currency, err := rates.resolve(" ")
+	assert.NoError(t, err)
+	assert.Equal(t, "NGN", currency)
+
+	_, err = rates.resolve("usd")
+	assert.EqualError(t, err, "unsupported currency")
+}
+
+func TestValidateExchangeRateRejectsRatesThatAreNotPositive(t *testing.T) {
+	for _, value := range []float64{0, -1550} {
+		rate := models.ExchangeRate{Currency: "usd", Rate: value}
+		assert.EqualError(t, validateExchangeRate(&rate), "rate must be greater than zero", value)
+	}
+
+	rate := models.ExchangeRate{Currency: " usd ", Rate: 1550}
+	assert.NoError(t, validateExchangeRate(&rate))
+	assert.Equal(t, "USD", rate.Currency)
+}
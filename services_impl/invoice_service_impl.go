@@ -0,0 +1,283 @@
+package services_impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	invoiceCounter    = "invoice"
+	invoiceSellerName = "ADHomes"
+)
+
+var errInvoiceNumberTaken = errors.New("invoice number already assigned")
+
+type invoiceServiceImpl struct {
+	orderRepo   *repositories.OrderRepository
+	paymentRepo *repositories.PaymentRepository
+	counterRepo *repositories.CounterRepository
+}
+
+func NewInvoiceService(
+	orderRepo *repositories.OrderRepository,
+	paymentRepo *repositories.PaymentRepository,
+	counterRepo *repositories.CounterRepository,
+) *invoiceServiceImpl {
+	return &invoiceServiceImpl{
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		counterRepo: counterRepo,
+	}
+}
+
+func (s *invoiceServiceImpl) GetUserInvoice(orderID, userID string) ([]byte, string, error) {
+	order, err := s.orderRepo.FindUserOrder(orderID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.render(order)
+}
+
+func (s *invoiceServiceImpl) GetInvoice(orderID string) ([]byte, string, error) {
+	order, err := s.orderRepo.FindOrderByID(orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.render(order)
+}
+
+func (s *invoiceServiceImpl) render(order models.Order) ([]byte, string, error) {
+	order, err := s.assignInvoiceNumber(order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	payments, err := s.paymentRepo.FindByOrderID(context.Background(), order.ID.Hex())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return renderInvoice(order, payments), order.InvoiceNumber, nil
+}
+
+// assignInvoiceNumber gives the order its invoice number the first time an invoice is requested.
+// The counter increment and the order update share a transaction, so numbers are
+// sequential without gaps even when two requests race for the same order.
+func (s *invoiceServiceImpl) assignInvoiceNumber(order models.Order) (models.Order, error) {
+	if order.InvoiceNumber != "" {
+		return order, nil
+	}
+
+	ctx := context.Background()
+	err := repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		seq, err := s.counterRepo.Next(sc, invoiceCounter)
+		if err != nil {
+			return err
+		}
+
+		number := formatInvoiceNumber(seq)
+		now := time.Now()
+		assigned, err := s.orderRepo.SetInvoiceNumber(sc, order.ID, number, now)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return errInvoiceNumberTaken
+		}
+
+		order.InvoiceNumber = number
+		order.InvoicedAt = &now
+		return nil
+	})
+
+	if errors.Is(err, errInvoiceNumberTaken) {
+		return s.orderRepo.FindOrderByID(order.ID.Hex())
+	}
+	return order, err
+}
+
+func formatInvoiceNumber(seq int64) string {
+	return fmt.Sprintf("INV-%06d", seq)
+}
+
+// -----------------------------
+// PDF LAYOUT
+// -----------------------------
+const (
+	invoiceMarginLeft  = 50.0
+	invoiceMarginRight = utils.PDFPageWidth - 50.0
+	invoiceTop         = utils.PDFPageHeight - 50.0
+	invoiceBottom      = 60.0
+	invoiceLineHeight  = 16.0
+)
+
+// invoiceWriter tracks the current line and starts new pages as content runs off the bottom.
+type invoiceWriter struct {
+	pdf *utils.PDF
+	y   float64
+}
+
+func (w *invoiceWriter) next(lines float64) {
+	w.y -= invoiceLineHeight * lines
+	if w.y < invoiceBottom {
+		w.pdf.AddPage()
+		w.y = invoiceTop
+	}
+}
+
+func (w *invoiceWriter) rule() {
+	w.pdf.Line(invoiceMarginLeft, w.y+invoiceLineHeight-4, invoiceMarginRight, w.y+invoiceLineHeight-4)
+}
+
+func (w *invoiceWriter) total(label string, amount models.Money, bold bool) {
+	w.pdf.Text(340, w.y, 10, bold, label)
+	w.pdf.TextRight(invoiceMarginRight, w.y, 10, bold, amount.String())
+	w.next(1)
+}
+
+// renderInvoice lays out the order as a PDF. Orders with a successful charge
+// are titled as a receipt, everything else as an invoice.
+func renderInvoice(order models.Order, payments []models.Payment) []byte {
+	title := "INVOICE"
+	for _, p := range payments {
+		if p.Type == models.PaymentTypeCharge && p.Status == "success" {
+			title = "RECEIPT"
+			break
+		}
+	}
+
+	w := &invoiceWriter{pdf: utils.NewPDF(), y: invoiceTop}
+
+	w.pdf.Text(invoiceMarginLeft, w.y, 18, true, invoiceSellerName)
+	w.pdf.TextRight(invoiceMarginRight, w.y, 18, true, title)
+	w.next(1.5)
+
+	issued := order.CreatedAt
+	if order.InvoicedAt != nil {
+		issued = *order.InvoicedAt
+	}
+	w.pdf.Text(invoiceMarginLeft, w.y, 10, false, "Invoice no: "+order.InvoiceNumber)
+	w.pdf.TextRight(invoiceMarginRight, w.y, 10, false, "Issued: "+issued.Format("02 Jan 2006"))
+	w.next(1)
+	w.pdf.Text(invoiceMarginLeft, w.y, 10, false, "Order: "+order.ID.Hex())
+	w.pdf.TextRight(invoiceMarginRight, w.y, 10, false, "Placed: "+order.CreatedAt.Format("02 Jan 2006"))
+	w.next(1)
+	w.pdf.Text(invoiceMarginLeft, w.y, 10, false, "Status: "+order.Status)
+	if order.PaymentStatus != "" {
+		w.pdf.TextRight(invoiceMarginRight, w.y, 10, false, "Payment: "+order.PaymentStatus)
+	}
+	w.next(2)
+
+	// Customer and delivery
+	w.pdf.Text(invoiceMarginLeft, w.y, 11, true, "Bill to")
+	w.pdf.Text(300, w.y, 11, true, "Deliver to")
+	w.next(1)
+
+	billTo := nonEmpty(order.CustomerName, order.CustomerEmail, order.CustomerPhone)
+	deliverTo := []string{"Pickup"}
+	if order.DeliveryType != models.DeliveryTypePickup {
+		a := order.ShippingAddress
+		deliverTo = nonEmpty(a.Street, strings.Join(nonEmpty(a.City, a.State), ", "), strings.Join(nonEmpty(a.Country, a.PostalCode), " "))
+	}
+	for i := 0; i < len(billTo) || i < len(deliverTo); i++ {
+		if i < len(billTo) {
+			w.pdf.Text(invoiceMarginLeft, w.y, 10, false, billTo[i])
+		}
+		if i < len(deliverTo) {
+			w.pdf.Text(300, w.y, 10, false, deliverTo[i])
+		}
+		w.next(1)
+	}
+	w.next(1)
+
+	// Items
+	itemHeader := func() {
+		w.pdf.Text(invoiceMarginLeft, w.y, 10, true, "Item")
+		w.pdf.TextRight(330, w.y, 10, true, "Qty")
+		w.pdf.TextRight(420, w.y, 10, true, "Unit price")
+		w.pdf.TextRight(470, w.y, 10, true, "VAT")
+		w.pdf.TextRight(invoiceMarginRight, w.y, 10, true, "Amount")
+		w.next(1)
+		w.rule()
+	}
+	itemHeader()
+
+	for _, item := range order.Items {
+		page := w.y
+		w.pdf.Text(invoiceMarginLeft, w.y, 10, false, truncateText(item.Name, 260, 10))
+		w.pdf.TextRight(330, w.y, 10, false, fmt.Sprintf("%d", item.Quantity))
+		w.pdf.TextRight(420, w.y, 10, false, item.UnitPrice.String())
+		w.pdf.TextRight(470, w.y, 10, false, fmt.Sprintf("%g%%", item.TaxRate))
+		w.pdf.TextRight(invoiceMarginRight, w.y, 10, false, item.Subtotal.String())
+		w.next(1)
+		if w.y > page {
+			itemHeader()
+		}
+	}
+	w.rule()
+	w.next(0.5)
+
+	// Totals
+	w.total("Subtotal", order.Subtotal, false)
+	if order.Discount.Amount > 0 {
+		label := "Discount"
+		if order.CouponCode != "" {
+			label += " (" + order.CouponCode + ")"
+		}
+		w.total(label, order.Discount.Neg(), false)
+	}
+	w.total("VAT", order.TaxAmount, false)
+	w.total("Delivery", order.DeliveryFee, false)
+	w.total("Total", order.TotalAmount, true)
+	w.next(1)
+
+	// Payments
+	if len(payments) > 0 {
+		w.pdf.Text(invoiceMarginLeft, w.y, 11, true, "Payments")
+		w.next(1)
+		for _, p := range payments {
+			amount := p.Amount
+			if p.Type == models.PaymentTypeRefund {
+				amount = amount.Neg()
+			}
+			w.pdf.Text(invoiceMarginLeft, w.y, 10, false, p.CreatedAt.Format("02 Jan 2006"))
+			w.pdf.Text(130, w.y, 10, false, strings.Join(nonEmpty(p.Type, p.Method, p.Status), " / "))
+			w.pdf.Text(300, w.y, 10, false, truncateText(p.Reference, 150, 10))
+			w.pdf.TextRight(invoiceMarginRight, w.y, 10, false, amount.String())
+			w.next(1)
+		}
+	}
+
+	return w.pdf.Bytes()
+}
+
+func nonEmpty(values ...string) []string {
+	out := []string{}
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// truncateText shortens s with "..." so that it fits within width points.
+func truncateText(s string, width, size float64) string {
+	if utils.TextWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && utils.TextWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
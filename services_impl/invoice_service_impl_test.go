@@ -0,0 +1,79 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestFormatInvoiceNumberIsZeroPadded(t *testing.T) {
+	assert.Equal(t, "INV-000042", formatInvoiceNumber(42))
+}
+
+func newMockInvoiceService(mt *mtest.T) *invoiceServiceImpl {
+	return NewInvoiceService(
+		repositories.NewOrderRepository(mt.DB.Collection("orders")),
+		repositories.NewPaymentRepository(mt.DB.Collection("payments")),
+		repositories.NewCounterRepository(mt.DB.Collection("counters")),
+	)
+}
+
+func TestClientSuppliedInvoiceNumberIsNotKept(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockInvoiceService(mt)
+
+		// What a client could send with a new order
+		forgedAt := time.Now()
+		order := models.Order{
+			ID:            primitive.NewObjectID(),
+			InvoiceNumber: "INV-000001",
+			InvoicedAt:    &forgedAt,
+		}
+		clearServerOwnedFields(&order)
+
+		mt.AddMockResponses(
+			findAndModifyReply(bson.D{{Key: "_id", Value: invoiceCounter}, {Key: "seq", Value: 42}}),
+			updateReply(1),
+			okReply(),
+		)
+
+		invoiced, err := s.assignInvoiceNumber(order)
+		require.NoError(t, err)
+		assert.Equal(t, "INV-000042", invoiced.InvoiceNumber)
+		assert.NotEqual(t, forgedAt, *invoiced.InvoicedAt)
+		assert.Equal(t, []string{"findAndModify", "update", "commitTransaction"}, sentCommands(mt))
+	})
+}
+
+func TestAssignInvoiceNumberOnlyOnce(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockInvoiceService(mt)
+		order := models.Order{ID: primitive.NewObjectID()}
+
+		// Another request numbered the order first: the guarded update matches nothing,
+		// the counter increment is rolled back and the stored number is used.
+		mt.AddMockResponses(
+			findAndModifyReply(bson.D{{Key: "_id", Value: invoiceCounter}, {Key: "seq", Value: 8}}),
+			updateReply(0),
+			okReply(),
+			findReply(mockDoc(t, models.Order{ID: order.ID, InvoiceNumber: "INV-000007"})),
+		)
+
+		invoiced, err := s.assignInvoiceNumber(order)
+		require.NoError(t, err)
+		assert.Equal(t, "INV-000007", invoiced.InvoiceNumber)
+		assert.Equal(t, []string{"findAndModify", "update", "abortTransaction", "find"}, sentCommands(mt))
+
+		filter, _ := sentUpdate(t, mt, 0)
+		assert.Equal(t, order.ID, filter.Lookup("_id").ObjectID())
+		assert.False(t, filter.Lookup("invoice_number", "$exists").Boolean())
+	})
+}
@@ -0,0 +1,88 @@
+package services_impl
+
+import (
+	"testing"
+
+	"adhomes-backend/config"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// withMockDB runs fn against a mock deployment standing in for config.DB. The
+// deployment answers each command, in order, with the next queued reply, so a test
+// queues one reply per command its flow sends, commitTransaction included.
+func withMockDB(t *testing.T, fn func(mt *mtest.T)) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("mock", func(mt *mtest.T) {
+		previous := config.DB
+		config.DB = mt.DB
+		defer func() { config.DB = previous }()
+
+		fn(mt)
+	})
+}
+
+// mockDoc converts v to the document a reply carries.
+func mockDoc(t *testing.T, v interface{}) bson.D {
+	raw, err := bson.Marshal(v)
+	require.NoError(t, err)
+
+	var doc bson.D
+	require.NoError(t, bson.Unmarshal(raw, &doc))
+	return doc
+}
+
+// Replies to the commands the repositories send.
+
+func okReply() bson.D { return mtest.CreateSuccessResponse() }
+
+func insertReply() bson.D { return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}) }
+
+func updateReply(modified int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: modified}, bson.E{Key: "nModified", Value: modified})
+}
+
+// findAndModifyReply answers FindOneAndUpdate; a nil doc means nothing matched.
+func findAndModifyReply(doc interface{}) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: doc})
+}
+
+// findReply answers Find, FindOne and Aggregate with a single batch.
+func findReply(docs ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "test.mock", mtest.FirstBatch, docs...)
+}
+
+func countReply(n int) bson.D { return findReply(bson.D{{Key: "n", Value: n}}) }
+
+// sentCommands lists the commands the flow sent, by name, in order.
+func sentCommands(mt *mtest.T) []string {
+	names := []string{}
+	for _, e := range mt.GetAllStartedEvents() {
+		names = append(names, e.CommandName)
+	}
+	return names
+}
+
+// sentCommand returns the nth (from 0) command named name the flow sent.
+func sentCommand(t *testing.T, mt *mtest.T, name string, nth int) *event.CommandStartedEvent {
+	for _, e := range mt.GetAllStartedEvents() {
+		if e.CommandName != name {
+			continue
+		}
+		if nth == 0 {
+			return e
+		}
+		nth--
+	}
+	t.Fatalf("no %s command #%d was sent", name, nth)
+	return nil
+}
+
+// sentUpdate returns the filter and update of the nth (from 0) update command the flow sent.
+func sentUpdate(t *testing.T, mt *mtest.T, nth int) (bson.Raw, bson.Raw) {
+	statement := sentCommand(t, mt, "update", nth).Command.Lookup("updates").Array().Index(0).Value().Document()
+	return statement.Lookup("q").Document(), statement.Lookup("u").Document()
+}
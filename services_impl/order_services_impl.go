@@ -1,25 +1,65 @@
 package services_impl
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
 
+	"adhomes-backend/config"
 	"adhomes-backend/models"
 	"adhomes-backend/repositories"
+	"adhomes-backend/services"
 	"adhomes-backend/utils"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type orderServiceImpl struct {
-	orderRepo   *repositories.OrderRepository
-	productRepo *repositories.ProductRepository
+	orderRepo    *repositories.OrderRepository
+	productRepo  *repositories.ProductRepository
+	paymentRepo  *repositories.PaymentRepository
+	walletRepo   *repositories.WalletRepository
+	couponRepo   *repositories.CouponRepository
+	taxRepo      *repositories.TaxRateRepository
+	rateRepo     *repositories.ExchangeRateRepository
+	zoneService  services.DeliveryZoneService
+	refunder     paymentRefunder
+	cancelPolicy config.CancellationPolicy
 }
 
-func NewOrderService(orderRepo *repositories.OrderRepository, productRepo *repositories.ProductRepository) *orderServiceImpl {
+func NewOrderService(
+	orderRepo *repositories.OrderRepository,
+	productRepo *repositories.ProductRepository,
+	paymentRepo *repositories.PaymentRepository,
+	walletRepo *repositories.WalletRepository,
+	couponRepo *repositories.CouponRepository,
+	taxRepo *repositories.TaxRateRepository,
+	rateRepo *repositories.ExchangeRateRepository,
+	zoneService services.DeliveryZoneService,
+	provider services.PaymentProvider,
+	cancelPolicy config.CancellationPolicy,
+) *orderServiceImpl {
 	return &orderServiceImpl{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
+		orderRepo:    orderRepo,
+		productRepo:  productRepo,
+		paymentRepo:  paymentRepo,
+		walletRepo:   walletRepo,
+		couponRepo:   couponRepo,
+		taxRepo:      taxRepo,
+		rateRepo:     rateRepo,
+		zoneService:  zoneService,
+		refunder:     newPaymentRefunder(paymentRepo, orderRepo, walletRepo, provider),
+		cancelPolicy: cancelPolicy,
 	}
 }
 
@@ -28,55 +68,374 @@ func NewOrderService(orderRepo *repositories.OrderRepository, productRepo *repos
 // -----------------------------
 func (s *orderServiceImpl) CreateOrder(order models.Order) (models.Order, error) {
 
-	if len(order.Items) == 0 {
-		return models.Order{}, errors.New("order must contain at least one item")
+	if order.UserID == "" {
+		return models.Order{}, errors.New("order must belong to a user")
 	}
 
-	var total float64
+	order.Guest = false
+	order.TrackingTokenHash = ""
 
-	for _, item := range order.Items {
-		productID, err := primitive.ObjectIDFromHex(item.ProductID)
-		if err != nil {
-			return models.Order{}, errors.New("invalid product ID")
-		}
+	return s.placeOrder(order, order.UserID)
+}
 
-		product, err := s.productRepo.FindByID(productID)
+// -----------------------------
+// GUEST CHECKOUT
+// -----------------------------
+
+// CreateGuestOrder places an order without an account. The returned tracking token
+// is the only way back to the order, so it is handed out once and never stored.
+func (s *orderServiceImpl) CreateGuestOrder(order models.Order) (models.Order, string, error) {
+	order.CustomerName = strings.TrimSpace(order.CustomerName)
+	order.CustomerEmail = strings.TrimSpace(order.CustomerEmail)
+	order.CustomerPhone = strings.TrimSpace(order.CustomerPhone)
+
+	if order.CustomerName == "" || order.CustomerEmail == "" || order.CustomerPhone == "" {
+		return models.Order{}, "", errors.New("customer name, email and phone are required")
+	}
+	if _, err := mail.ParseAddress(order.CustomerEmail); err != nil {
+		return models.Order{}, "", errors.New("invalid customer email")
+	}
+
+	token, hash, err := newTrackingToken()
+	if err != nil {
+		return models.Order{}, "", err
+	}
+
+	order.UserID = ""
+	order.Guest = true
+	order.TrackingTokenHash = hash
+
+	created, err := s.placeOrder(order, "guest")
+	if err != nil {
+		return models.Order{}, "", err
+	}
+	return created, token, nil
+}
+
+func (s *orderServiceImpl) GetOrderByTrackingToken(token string) (models.Order, error) {
+	if token == "" {
+		return models.Order{}, errors.New("Order not found")
+	}
+	return s.orderRepo.FindByTrackingTokenHash(hashTrackingToken(token))
+}
+
+// ClaimGuestOrder moves a guest order, and the payments made on it, into the account
+// of userID. The account must use the email the order was placed with.
+func (s *orderServiceImpl) ClaimGuestOrder(token string, userID string) (models.Order, error) {
+	order, err := s.GetOrderByTrackingToken(token)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if !order.Guest {
+		return models.Order{}, errors.New("Order not found")
+	}
+	if order.UserID == userID {
+		return order, nil
+	}
+	if order.UserID != "" {
+		return models.Order{}, errors.New("order has already been claimed")
+	}
+
+	// Accounts are identified by their email address
+	if !strings.EqualFold(order.CustomerEmail, userID) {
+		return models.Order{}, errors.New("order was placed with a different email")
+	}
+
+	id := order.ID.Hex()
+	err = repositories.WithTransaction(context.Background(), func(sc mongo.SessionContext) error {
+		claimed, err := s.orderRepo.ClaimGuestOrder(sc, order.ID, userID)
 		if err != nil {
-			return models.Order{}, err
+			return err
+		}
+		if !claimed {
+			return errors.New("order has already been claimed")
 		}
 
-		total += product.Price * float64(item.Quantity)
+		return s.paymentRepo.AssignUser(sc, id, userID)
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	return s.orderRepo.FindUserOrder(id, userID)
+}
+
+// trackingTokenBytes gives tracking tokens 256 bits of entropy.
+const trackingTokenBytes = 32
+
+// newTrackingToken returns a random tracking token and the hash stored in its place.
+func newTrackingToken() (string, string, error) {
+	buf := make([]byte, trackingTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashTrackingToken(token), nil
+}
+
+func hashTrackingToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// couponHolder is who a coupon redemption on order is counted against. Guests are
+// counted by email, and stay so after claiming the order so a release matches the redemption.
+func couponHolder(order models.Order) string {
+	if order.Guest {
+		return "guest:" + strings.ToLower(order.CustomerEmail)
+	}
+	return order.UserID
+}
+
+// clearServerOwnedFields drops what only the server records on an order, should a
+// client send it with a new one. A forged invoice number would otherwise be kept,
+// since invoices never renumber an order that has one.
+func clearServerOwnedFields(order *models.Order) {
+	order.InvoiceNumber = ""
+	order.InvoicedAt = nil
+	order.CancellationReason = ""
+	order.CancelledAt = nil
+}
+
+// placeOrder prices and saves a new order on behalf of actor.
+func (s *orderServiceImpl) placeOrder(order models.Order, actor string) (models.Order, error) {
+	if len(order.Items) == 0 {
+		return models.Order{}, errors.New("order must contain at least one item")
+	}
+
+	clearServerOwnedFields(&order)
+	now := time.Now()
+
+	coupon, err := s.priceOrder(&order, nil, now)
+	if err != nil {
+		return models.Order{}, err
 	}
 
 	order.ID = primitive.NewObjectID()
-	order.TotalAmount = total
 	order.PaymentStatus = "unpaid"
-	order.Status = "pending"
-	order.CreatedAt = time.Now()
-	order.UpdatedAt = time.Now()
+	order.Status = utils.OrderStatusPending
+	order.StatusHistory = []models.OrderStatusChange{{
+		To:        utils.OrderStatusPending,
+		Actor:     actor,
+		Reason:    "order created",
+		ChangedAt: now,
+	}}
+	order.CreatedAt = now
+	order.UpdatedAt = now
 
-	return s.orderRepo.CreateOrder(order)
+	// Reserve stock for every line and save the order in one transaction,
+	// so a single short item leaves every product untouched.
+	var created models.Order
+	err = repositories.WithTransaction(context.Background(), func(sc mongo.SessionContext) error {
+		if err := s.reserveStock(sc, order.Items); err != nil {
+			return err
+		}
+
+		if coupon != nil {
+			if err := s.couponRepo.Redeem(sc, *coupon, couponHolder(order)); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		created, err = s.orderRepo.CreateOrder(sc, order)
+		return err
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	return created, nil
 }
 
 // -----------------------------
 // APPROVE ORDER (ADMIN)
 // -----------------------------
-func (s *orderServiceImpl) ApproveOrder(id string) error {
-	return s.orderRepo.UpdateOrderStatus(id, "approved")
+func (s *orderServiceImpl) ApproveOrder(id string, actor string) error {
+	return transitionOrder(context.Background(), s.orderRepo, id, utils.OrderStatusApproved, actor, "approved by admin")
 }
 
 // -----------------------------
 // CANCEL ORDER
 // -----------------------------
-func (s *orderServiceImpl) CancelOrder(id string) error {
-	return s.orderRepo.UpdateOrderStatus(id, "cancelled")
+func (s *orderServiceImpl) CancelOrder(id string, actor string, reason string) error {
+	order, err := s.orderRepo.FindOrderByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.cancelOrder(order, actor, reason)
+}
+
+// -----------------------------
+// EXPIRE UNPAID ORDERS (BACKGROUND)
+// -----------------------------
+
+// expiryBatchSize bounds the work done per run; later runs pick up the rest.
+const expiryBatchSize = 100
+
+func (s *orderServiceImpl) ExpireUnpaidOrders(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	statuses := []string{utils.OrderStatusPending, utils.OrderStatusApproved}
+
+	orders, err := s.orderRepo.FindUnpaidBefore(ctx, statuses, cutoff, expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	reason := fmt.Sprintf("unpaid for more than %s", olderThan)
+	expired := 0
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return expired, ctx.Err()
+		}
+
+		// An order paid or cancelled in the meantime fails the status check and is skipped.
+		if err := s.cancelOrder(order, "system", reason); err != nil {
+			log.Printf("failed to expire order %s: %v", order.ID.Hex(), err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// -----------------------------
+// CANCEL ORDER (CUSTOMER)
+// -----------------------------
+func (s *orderServiceImpl) CancelMyOrder(id string, userID string, reason string) (models.Order, error) {
+	order, err := s.orderRepo.FindUserOrder(id, userID)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if !containsStatus(s.cancelPolicy.Statuses, order.Status) {
+		return models.Order{}, errors.New("order cannot be cancelled in its current status")
+	}
+
+	if s.cancelPolicy.Window > 0 && time.Since(order.CreatedAt) > s.cancelPolicy.Window {
+		return models.Order{}, errors.New("cancellation window has passed")
+	}
+
+	payments, err := s.paymentRepo.FindByOrderID(context.Background(), id)
+	if err != nil {
+		return models.Order{}, err
+	}
+	for _, p := range payments {
+		if p.Type != models.PaymentTypeRefund && p.Status == "success" && p.Method != "wallet" {
+			return models.Order{}, errors.New("card payments can only be cancelled by support")
+		}
+	}
+
+	if err := s.cancelOrder(order, userID, reason); err != nil {
+		return models.Order{}, err
+	}
+
+	return s.orderRepo.FindUserOrder(id, userID)
+}
+
+// cancelOrder cancels the order, puts its items back in stock and refunds what was
+// paid on it: wallet charges are credited back together with the cancellation, and
+// card charges are refunded through the provider once it has committed. Wallet money
+// held for a split payment goes back too.
+func (s *orderServiceImpl) cancelOrder(order models.Order, actor string, reason string) error {
+	change, err := statusChange(order, utils.OrderStatusCancelled, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	id := order.ID.Hex()
+
+	var refunds []reservedRefund
+	err = repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := s.orderRepo.TransitionStatus(sc, id, order.Status, change); err != nil {
+			return err
+		}
+
+		if err := s.releaseStock(sc, order.Items); err != nil {
+			return err
+		}
+
+		if !order.CouponID.IsZero() {
+			if err := s.couponRepo.Release(sc, order.CouponID, couponHolder(order)); err != nil {
+				return err
+			}
+		}
+
+		payments, err := s.paymentRepo.FindByOrderID(sc, id)
+		if err != nil {
+			return err
+		}
+
+		// Held wallet parts go back to the wallet; whatever was paid is refunded in full
+		refunds = nil
+		for _, charge := range payments {
+			if charge.Type == models.PaymentTypeRefund || charge.Type == models.PaymentTypeTopUp {
+				continue
+			}
+
+			switch charge.Status {
+			case "held":
+				if _, err := releaseWalletHold(sc, s.paymentRepo, s.walletRepo, charge); err != nil {
+					return err
+				}
+			case "success":
+				left := charge.Amount.Sub(refundedAmount(payments, charge.ID.Hex()))
+				if left.IsZero() || left.IsNegative() {
+					continue
+				}
+
+				refund, locked, err := s.refunder.reserve(sc, charge, nil, reason, false)
+				if err != nil {
+					return err
+				}
+				refunds = append(refunds, reservedRefund{charge: locked, refund: refund})
+			}
+		}
+
+		return s.orderRepo.MarkCancelled(sc, id, reason, change.ChangedAt)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Card refunds go to the provider once the cancellation is committed. One it turns
+	// down is released again, leaving the charge paid for support to refund by hand.
+	for _, r := range refunds {
+		if err := s.refunder.send(ctx, r.charge, r.refund, reason); err != nil {
+			return fmt.Errorf("order was cancelled but refunding payment %s failed: %w", r.charge.Reference, err)
+		}
+	}
+	return nil
+}
+
+// settledCharge returns the successful charge among an order's payments, if any.
+func settledCharge(payments []models.Payment) *models.Payment {
+	for i, p := range payments {
+		if p.Type != models.PaymentTypeRefund && p.Status == "success" {
+			return &payments[i]
+		}
+	}
+	return nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == utils.NormalizeStatus(status) {
+			return true
+		}
+	}
+	return false
 }
 
 // -----------------------------
 // GET ORDER BY ID
 // -----------------------------
-func (s *orderServiceImpl) GetOrderByID(id string) (models.Order, error) {
-	return s.orderRepo.FindOrderByID(id)
+func (s *orderServiceImpl) GetOrderByID(id string, userID string) (models.Order, error) {
+	return s.orderRepo.FindUserOrder(id, userID)
 }
 
 // -----------------------------
@@ -89,33 +448,451 @@ func (s *orderServiceImpl) GetOrdersByUserID(userID string) ([]models.Order, err
 // -----------------------------
 // GET ALL ORDERS (ADMIN)
 // -----------------------------
-func (s *orderServiceImpl) GetAllOrders() ([]models.Order, error) {
-	return s.orderRepo.FindAll()
+func (s *orderServiceImpl) GetAllOrders(query models.OrderListQuery) ([]models.Order, models.Pagination, error) {
+	filter, err := buildOrderFilter(query)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	sort, err := buildOrderSort(query.Sort)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	page, limit := query.Page, query.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultOrderPageSize
+	}
+	if limit > maxOrderPageSize {
+		limit = maxOrderPageSize
+	}
+
+	orders, total, err := s.orderRepo.FindPage(filter, sort, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	return orders, models.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	}, nil
 }
 
 // -----------------------------
 // UPDATE ORDER (FULL UPDATE)
 // -----------------------------
-func (s *orderServiceImpl) UpdateOrder(id string, order models.Order) (models.Order, error) {
+func (s *orderServiceImpl) UpdateOrder(id string, userID string, order models.Order) (models.Order, error) {
+	existing, err := s.orderRepo.FindUserOrder(id, userID)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if utils.NormalizeStatus(existing.Status) != utils.OrderStatusPending || existing.IsPaid() {
+		return models.Order{}, errors.New("only pending, unpaid orders can be updated")
+	}
+
+	if len(order.Items) == 0 {
+		order.Items = existing.Items
+	}
+
+	// Lines already on the order keep their original snapshot; only new products are priced now.
+	// The client-supplied total is ignored and recomputed from the snapshots.
+	// The coupon was redeemed when the order was placed; it cannot be swapped afterwards
+	// and is judged as of that moment.
+	order.CouponCode = existing.CouponCode
+	order.Currency = existing.Currency
+	if _, err := s.priceOrder(&order, existing.Items, existing.CreatedAt); err != nil {
+		return models.Order{}, err
+	}
 	order.UpdatedAt = time.Now()
-	return s.orderRepo.UpdateOrder(id, order)
+
+	var updated models.Order
+	err = repositories.WithTransaction(context.Background(), func(sc mongo.SessionContext) error {
+		if err := s.releaseStock(sc, existing.Items); err != nil {
+			return err
+		}
+		if err := s.reserveStock(sc, order.Items); err != nil {
+			return err
+		}
+
+		var err error
+		updated, err = s.orderRepo.UpdateOrder(sc, id, userID, existing.Status, order)
+		return err
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	return updated, nil
 }
 
 // -----------------------------
 // UPDATE ORDER STATUS ONLY
 // -----------------------------
-func (s *orderServiceImpl) UpdateOrderStatus(id string, newStatus string) error {
+
+// UpdateOrderStatus is the customer's status change, which can only be a cancellation.
+// Staff move the order through fulfilment.
+func (s *orderServiceImpl) UpdateOrderStatus(id string, userID string, newStatus string) error {
 
 	if !utils.IsValidStatus(newStatus) {
 		return errors.New("invalid order status")
 	}
 
-	return s.orderRepo.UpdateOrderStatus(id, newStatus)
+	if utils.NormalizeStatus(newStatus) != utils.OrderStatusCancelled {
+		return errors.New("customers can only cancel orders")
+	}
+
+	_, err := s.CancelMyOrder(id, userID, "")
+	return err
+}
+
+// -----------------------------
+// FULFILMENT STATUS (ADMIN)
+// -----------------------------
+func (s *orderServiceImpl) UpdateFulfilmentStatus(id string, actor string, status string) error {
+	if !utils.IsFulfilmentStatus(status) {
+		return errors.New("invalid fulfilment status")
+	}
+	return transitionOrder(context.Background(), s.orderRepo, id, utils.NormalizeStatus(status), actor, "")
 }
 
 // -----------------------------
 // DELETE ORDER
 // -----------------------------
-func (s *orderServiceImpl) DeleteOrder(id string) error {
-	return s.orderRepo.DeleteOrder(id)
+
+// DeleteOrder withdraws a pending, unpaid order. The order is cancelled rather than
+// removed, so its stock and coupon are released with it and any payment started on
+// it still has an order to point at.
+func (s *orderServiceImpl) DeleteOrder(id string, userID string) error {
+	order, err := s.orderRepo.FindUserOrder(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if utils.NormalizeStatus(order.Status) != utils.OrderStatusPending || order.IsPaid() {
+		return errors.New("only pending, unpaid orders can be deleted")
+	}
+
+	return s.cancelOrder(order, userID, "deleted by customer")
+}
+
+// -----------------------------
+// ADMIN LISTING
+// -----------------------------
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// orderSortFields maps the sort keys accepted by the admin listing to document fields.
+var orderSortFields = map[string]string{
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"total_amount": "total_amount.amount",
+	"status":       "status",
+}
+
+func buildOrderFilter(query models.OrderListQuery) (bson.M, error) {
+	filter := bson.M{}
+
+	if query.Status != "" {
+		filter["status"] = utils.NormalizeStatus(query.Status)
+	}
+	if query.PaymentStatus != "" {
+		filter["payment_status"] = query.PaymentStatus
+	}
+	if query.DeliveryType != "" {
+		filter["delivery_type"] = query.DeliveryType
+	}
+	if query.CustomerEmail != "" {
+		filter["customer_email"] = bson.M{
+			"$regex":   "^" + regexp.QuoteMeta(query.CustomerEmail),
+			"$options": "i",
+		}
+	}
+	if query.CustomerPhone != "" {
+		filter["customer_phone"] = query.CustomerPhone
+	}
+
+	createdAt := bson.M{}
+	if query.From != "" {
+		from, err := parseDate(query.From, false)
+		if err != nil {
+			return nil, errors.New("invalid from date")
+		}
+		createdAt["$gte"] = from
+	}
+	if query.To != "" {
+		to, err := parseDate(query.To, true)
+		if err != nil {
+			return nil, errors.New("invalid to date")
+		}
+		createdAt["$lte"] = to
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	total := bson.M{}
+	if query.MinTotal != nil {
+		total["$gte"] = *query.MinTotal
+	}
+	if query.MaxTotal != nil {
+		total["$lte"] = *query.MaxTotal
+	}
+	if len(total) > 0 {
+		filter["total_amount.amount"] = total
+	}
+
+	return filter, nil
+}
+
+// buildOrderSort turns "total_amount" or "-created_at" into a sort document.
+// The _id tie-breaker keeps pages stable when sort values repeat.
+func buildOrderSort(key string) (bson.D, error) {
+	if key == "" {
+		key = "-created_at"
+	}
+
+	direction := 1
+	if strings.HasPrefix(key, "-") {
+		direction = -1
+		key = strings.TrimPrefix(key, "-")
+	}
+
+	field, ok := orderSortFields[key]
+	if !ok {
+		return nil, errors.New("invalid sort key")
+	}
+
+	return bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}, nil
+}
+
+// parseDate accepts RFC3339 timestamps or plain dates; a plain end date covers the whole day.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// -----------------------------
+// PRICING
+// -----------------------------
+
+// priceOrder snapshots the order's lines and fills in its breakdown: subtotal of the lines,
+// coupon discount, VAT, delivery fee for the chosen delivery type and address, and total.
+// Everything is priced in the order's currency (the base currency if none was chosen).
+// It returns the coupon applied, if any, so the caller can redeem it.
+func (s *orderServiceImpl) priceOrder(order *models.Order, previous []models.OrderItem, pricedAt time.Time) (*models.Coupon, error) {
+	rates, err := loadExchangeTable(s.rateRepo)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := rates.resolve(order.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.priceItems(order.Items, previous, rates, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	subtotal := models.NewMoney(0, currency)
+	var weight float64
+	for _, item := range items {
+		subtotal = subtotal.Add(item.Subtotal)
+		weight += item.WeightKg * float64(item.Quantity)
+	}
+
+	if order.DeliveryType == "" {
+		order.DeliveryType = models.DeliveryTypeDelivery
+	}
+
+	deliveryFee, err := s.zoneService.QuoteDeliveryFee(order.DeliveryType, order.ShippingAddress, subtotal, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	var coupon *models.Coupon
+	discount := models.NewMoney(0, subtotal.Currency)
+	if order.CouponCode != "" {
+		found, err := s.couponRepo.FindByCode(normalizeCouponCode(order.CouponCode))
+		if err != nil {
+			if err.Error() == "coupon not found" {
+				return nil, errors.New("invalid coupon code")
+			}
+			return nil, err
+		}
+
+		// Coupon amounts are set in the base currency
+		local := found
+		if local.Amount, err = rates.convert(found.Amount, currency); err != nil {
+			return nil, err
+		}
+		if local.MinOrderValue, err = rates.convert(found.MinOrderValue, currency); err != nil {
+			return nil, err
+		}
+
+		discount, err = couponDiscount(local, items, subtotal, pricedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		coupon = &found
+		order.CouponCode = found.Code
+		order.CouponID = found.ID
+	}
+
+	taxRates, err := s.taxRepo.FindActive()
+	if err != nil {
+		return nil, err
+	}
+	taxTotal, exclusiveTax := applyTax(taxRates, items, order.ShippingAddress.State, currency)
+
+	order.Currency = currency
+	order.ExchangeRate = rates[currency]
+	order.Items = items
+	order.Subtotal = subtotal
+	order.Discount = discount
+	order.TaxAmount = taxTotal
+	order.DeliveryFee = deliveryFee
+	order.TotalAmount = subtotal.Sub(discount).Add(exclusiveTax).Add(deliveryFee)
+	return coupon, nil
+}
+
+// priceItems validates the requested lines and snapshots product details onto each of them.
+// Stock is not checked here; reserveStock enforces it atomically.
+// A line whose product already appears in previous reuses that snapshot instead of the
+// current catalogue entry, so editing an order never reprices what was already on it.
+func (s *orderServiceImpl) priceItems(requested []models.OrderItem, previous []models.OrderItem, rates exchangeTable, currency string) ([]models.OrderItem, error) {
+	snapshots := make(map[string]models.OrderItem, len(previous))
+	for _, item := range previous {
+		snapshots[item.ProductID] = item
+	}
+
+	items := make([]models.OrderItem, 0, len(requested))
+	for _, item := range requested {
+		if item.Quantity <= 0 {
+			return nil, errors.New("item quantity must be greater than zero")
+		}
+
+		productID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return nil, errors.New("invalid product ID")
+		}
+
+		product, err := s.productRepo.FindByID(productID)
+		if err != nil {
+			return nil, err
+		}
+
+		line, ok := snapshots[item.ProductID]
+		if !ok {
+			price, err := rates.productPrice(*product, currency)
+			if err != nil {
+				return nil, err
+			}
+
+			line = models.OrderItem{
+				ProductID: item.ProductID,
+				Name:      product.Name,
+				Category:  product.Category,
+				ImageURL:  product.ImageURL,
+				WeightKg:  product.WeightKg,
+				UnitPrice: price,
+			}
+		}
+		line.Quantity = item.Quantity
+		line.Subtotal = line.UnitPrice.Mul(int64(item.Quantity))
+
+		items = append(items, line)
+	}
+
+	return items, nil
+}
+
+// -----------------------------
+// STOCK
+// -----------------------------
+
+// reserveStock decrements stock for every item; callers run it inside a transaction
+// so a failure on any line rolls back the lines already reserved.
+func (s *orderServiceImpl) reserveStock(ctx context.Context, items []models.OrderItem) error {
+	for _, item := range items {
+		productID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return errors.New("invalid product ID")
+		}
+
+		if err := s.productRepo.DecrementStock(ctx, productID, item.Quantity); err != nil {
+			return fmt.Errorf("%w for %s", err, item.Name)
+		}
+	}
+	return nil
+}
+
+// releaseStock returns the items of a cancelled order to stock.
+func (s *orderServiceImpl) releaseStock(ctx context.Context, items []models.OrderItem) error {
+	for _, item := range items {
+		productID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return errors.New("invalid product ID")
+		}
+
+		if err := s.productRepo.IncrementStock(ctx, productID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// -----------------------------
+// STATUS TRANSITIONS
+// -----------------------------
+
+// statusChange validates that order may move to status to and builds the history entry for it.
+// Moves the lifecycle does not allow are rejected with a *utils.InvalidTransitionError.
+func statusChange(order models.Order, to, actor, reason string) (models.OrderStatusChange, error) {
+	if err := utils.ValidateTransition(order.Status, to); err != nil {
+		return models.OrderStatusChange{}, err
+	}
+
+	return models.OrderStatusChange{
+		From:      utils.NormalizeStatus(order.Status),
+		To:        to,
+		Actor:     actor,
+		Reason:    reason,
+		ChangedAt: time.Now(),
+	}, nil
+}
+
+// transitionOrder moves an order to status to when no other document has to change with it.
+func transitionOrder(ctx context.Context, orderRepo *repositories.OrderRepository, id, to, actor, reason string) error {
+	order, err := orderRepo.FindOrderByID(id)
+	if err != nil {
+		return err
+	}
+
+	change, err := statusChange(order, to, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	return orderRepo.TransitionStatus(ctx, id, order.Status, change)
 }
@@ -0,0 +1,251 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/config"
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+	"adhomes-backend/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newMockOrderService(mt *mtest.T, provider services.PaymentProvider) *orderServiceImpl {
+	rateRepo := repositories.NewExchangeRateRepository(mt.DB.Collection("exchange_rates"))
+	return NewOrderService(
+		repositories.NewOrderRepository(mt.DB.Collection("orders")),
+		repositories.NewProductRepository(mt.DB.Collection("products")),
+		repositories.NewPaymentRepository(mt.DB.Collection("payments")),
+		repositories.NewWalletRepository(),
+		repositories.NewCouponRepository(mt.DB.Collection("coupons"), mt.DB.Collection("coupon_usages")),
+		repositories.NewTaxRateRepository(mt.DB.Collection("tax_rates")),
+		rateRepo,
+		NewDeliveryZoneService(repositories.NewDeliveryZoneRepository(mt.DB.Collection("delivery_zones")), rateRepo),
+		provider,
+		config.CancellationPolicy{Statuses: []string{utils.OrderStatusPending, utils.OrderStatusPaid}},
+	)
+}
+
+func TestCreateOrderDropsServerOwnedFields(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockOrderService(mt, &stubProvider{})
+		product := models.Product{ID: primitive.NewObjectID(), Name: "Chair", Price: ngn(2500000), Stock: 3}
+
+		forgedAt := time.Now()
+		mt.AddMockResponses(
+			findReply(), // exchange rates
+			findReply(mockDoc(t, product)),
+			findReply(), // tax rates
+			updateReply(1),
+			insertReply(),
+			okReply(),
+		)
+
+		created, err := s.CreateOrder(models.Order{
+			UserID:             "ada@example.com",
+			DeliveryType:       models.DeliveryTypePickup,
+			Items:              []models.OrderItem{{ProductID: product.ID.Hex(), Quantity: 1}},
+			InvoiceNumber:      "INV-000001",
+			InvoicedAt:         &forgedAt,
+			CancellationReason: "forged",
+			CancelledAt:        &forgedAt,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, created.InvoiceNumber)
+		assert.Nil(t, created.InvoicedAt)
+
+		inserted := sentCommand(t, mt, "insert", 0).Command.Lookup("documents").Array().Index(0).Value().Document()
+		for _, field := range []string{"invoice_number", "invoiced_at", "cancellation_reason", "cancelled_at"} {
+			_, err := inserted.LookupErr(field)
+			assert.Error(t, err, field)
+		}
+		assert.Equal(t, "unpaid", inserted.Lookup("payment_status").StringValue())
+	})
+}
+
+func TestDeleteOrderRejectsPaidOrders(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockOrderService(mt, &stubProvider{})
+		order := models.Order{ID: primitive.NewObjectID(), UserID: "ada@example.com", Status: utils.OrderStatusPending, PaymentStatus: "paid"}
+
+		mt.AddMockResponses(findReply(mockDoc(t, order)))
+
+		err := s.DeleteOrder(order.ID.Hex(), order.UserID)
+		assert.EqualError(t, err, "only pending, unpaid orders can be deleted")
+		assert.Equal(t, []string{"find"}, sentCommands(mt))
+	})
+}
+
+func TestDeleteOrderCancelsAndReleasesStock(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockOrderService(mt, &stubProvider{})
+		productID := primitive.NewObjectID()
+		order := models.Order{
+			ID:            primitive.NewObjectID(),
+			UserID:        "ada@example.com",
+			Status:        utils.OrderStatusPending,
+			PaymentStatus: "unpaid",
+			Items:         []models.OrderItem{{ProductID: productID.Hex(), Quantity: 2}},
+		}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			updateReply(1), // status
+			updateReply(1), // stock
+			findReply(),    // payments
+			updateReply(1), // cancellation
+			okReply(),
+		)
+
+		require.NoError(t, s.DeleteOrder(order.ID.Hex(), order.UserID))
+		assert.Equal(t, []string{"find", "update", "update", "find", "update", "commitTransaction"}, sentCommands(mt))
+
+		_, update := sentUpdate(t, mt, 0)
+		assert.Equal(t, utils.OrderStatusCancelled, update.Lookup("$set", "status").StringValue())
+
+		filter, update := sentUpdate(t, mt, 1)
+		assert.Equal(t, productID, filter.Lookup("_id").ObjectID())
+		assert.EqualValues(t, 2, update.Lookup("$inc", "stock").AsInt64())
+	})
+}
+
+func cardPaidOrder() (models.Order, models.Payment) {
+	order := models.Order{
+		ID:            primitive.NewObjectID(),
+		UserID:        "ada@example.com",
+		Status:        utils.OrderStatusPending,
+		PaymentStatus: "paid",
+	}
+	charge := models.Payment{
+		ID:        primitive.NewObjectID(),
+		OrderID:   order.ID.Hex(),
+		UserID:    order.UserID,
+		Type:      models.PaymentTypeCharge,
+		Amount:    ngn(1500000),
+		Method:    "paystack",
+		Status:    "success",
+		Reference: "ref-1",
+	}
+	return order, charge
+}
+
+func TestCancelOrderRefundsCardPayments(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		provider := &stubProvider{}
+		s := newMockOrderService(mt, provider)
+		order, charge := cardPaidOrder()
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			updateReply(1),                 // status
+			findReply(mockDoc(t, charge)),  // payments
+			updateReply(1),                 // lock
+			findReply(mockDoc(t, charge)),  // payments, locked
+			insertReply(),                  // refund
+			updateReply(1), updateReply(1), // charge and order payment status
+			updateReply(1), // cancellation
+			okReply(),
+		)
+
+		require.NoError(t, s.CancelOrder(order.ID.Hex(), "admin", "out of stock"))
+
+		require.Len(t, provider.refunds, 1)
+		assert.Equal(t, "ref-1", provider.refunds[0].Reference)
+		assert.Equal(t, ngn(1500000), provider.refunds[0].Amount)
+
+		refund := sentCommand(t, mt, "insert", 0).Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, models.PaymentTypeRefund, refund.Lookup("type").StringValue())
+		assert.Equal(t, "pending", refund.Lookup("status").StringValue())
+		assert.Equal(t, charge.ID.Hex(), refund.Lookup("refund_of").StringValue())
+
+		_, update := sentUpdate(t, mt, 3)
+		assert.Equal(t, "refunded", update.Lookup("$set", "payment_status").StringValue())
+		_, update = sentUpdate(t, mt, 4)
+		_, err := update.LookupErr("$set", "payment_status")
+		assert.Error(t, err, "cancellation must leave the payment status to the refund")
+	})
+}
+
+func TestCancelOrderReleasesRefundTheProviderTurnsDown(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		provider := &stubProvider{refundErr: &services.ProviderError{Provider: "paystack", Message: "declined"}}
+		s := newMockOrderService(mt, provider)
+		order, charge := cardPaidOrder()
+		refund := models.Payment{
+			ID:       primitive.NewObjectID(),
+			OrderID:  charge.OrderID,
+			Type:     models.PaymentTypeRefund,
+			RefundOf: charge.ID.Hex(),
+			Amount:   charge.Amount,
+			Status:   "failed",
+		}
+		refunded := charge
+		refunded.Status = "refunded"
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			updateReply(1),
+			findReply(mockDoc(t, charge)),
+			updateReply(1),
+			findReply(mockDoc(t, charge)),
+			insertReply(),
+			updateReply(1), updateReply(1),
+			updateReply(1),
+			okReply(),
+			// release
+			updateReply(1),
+			findReply(mockDoc(t, refunded), mockDoc(t, refund)),
+			updateReply(1), updateReply(1),
+			okReply(),
+		)
+
+		err := s.CancelOrder(order.ID.Hex(), "admin", "out of stock")
+		assert.ErrorContains(t, err, "order was cancelled but refunding payment ref-1 failed")
+
+		// The charge is paid again so support can refund it by hand
+		_, update := sentUpdate(t, mt, 6)
+		assert.Equal(t, "success", update.Lookup("$set", "status").StringValue())
+		_, update = sentUpdate(t, mt, 7)
+		assert.Equal(t, "paid", update.Lookup("$set", "payment_status").StringValue())
+	})
+}
+
+func TestUpdateOrderLeavesAnOrderPaidInTheMeantime(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockOrderService(mt, &stubProvider{})
+		productID := primitive.NewObjectID()
+		existing := models.Order{
+			ID:            primitive.NewObjectID(),
+			UserID:        "ada@example.com",
+			Status:        utils.OrderStatusPending,
+			PaymentStatus: "unpaid",
+			DeliveryType:  models.DeliveryTypePickup,
+			Items:         []models.OrderItem{{ProductID: productID.Hex(), Name: "Chair", Quantity: 1, UnitPrice: ngn(2500000), Subtotal: ngn(2500000)}},
+		}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, existing)),
+			findReply(), // exchange rates
+			findReply(mockDoc(t, models.Product{ID: productID, Name: "Chair", Price: ngn(2500000), Stock: 3})),
+			findReply(),    // tax rates
+			updateReply(1), // stock released
+			updateReply(1), // stock reserved
+			findAndModifyReply(nil),
+			okReply(),
+		)
+
+		_, err := s.UpdateOrder(existing.ID.Hex(), existing.UserID, models.Order{DeliveryType: models.DeliveryTypePickup})
+		assert.EqualError(t, err, "only pending, unpaid orders can be updated")
+
+		filter := sentCommand(t, mt, "findAndModify", 0).Command.Lookup("query").Document()
+		assert.Equal(t, utils.OrderStatusPending, filter.Lookup("status").StringValue())
+		_, err = filter.LookupErr("payment_status", "$nin")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,147 @@
+package services_impl
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// paymentRefunder gives money back on a charge. Refunds are recorded in a transaction
+// first and only then sent to the provider, so that a second refund racing the first
+// already counts it against what is left of the charge. Admin refunds, cancellations
+// and returns all go through it.
+type paymentRefunder struct {
+	paymentRepo *repositories.PaymentRepository
+	walletRepo  *repositories.WalletRepository
+	settler     paymentSettler
+	provider    services.PaymentProvider
+}
+
+func newPaymentRefunder(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository, walletRepo *repositories.WalletRepository, provider services.PaymentProvider) paymentRefunder {
+	return paymentRefunder{
+		paymentRepo: paymentRepo,
+		walletRepo:  walletRepo,
+		settler:     paymentSettler{paymentRepo: paymentRepo, orderRepo: orderRepo, walletRepo: walletRepo},
+		provider:    provider,
+	}
+}
+
+// reservedRefund is a refund recorded by reserve together with its charge as locked.
+type reservedRefund struct {
+	charge models.Payment
+	refund models.Payment
+}
+
+// reserve records a refund of charge for requested, or for all that is left of it when
+// requested is nil, and must run inside a transaction. Refunds to the wallet, which
+// wallet charges always get, are credited and complete here, opening a wallet for
+// customers who only ever paid by card; card refunds stay pending until send hands
+// them to the provider. It returns the refund and the charge as it was when locked.
+func (r paymentRefunder) reserve(sc context.Context, charge models.Payment, requested *models.Money, reason string, toWallet bool) (models.Payment, models.Payment, error) {
+	if err := r.paymentRepo.Lock(sc, charge.ID); err != nil {
+		return models.Payment{}, charge, err
+	}
+
+	payments, err := r.paymentRepo.FindByOrderID(sc, charge.OrderID)
+	if err != nil {
+		return models.Payment{}, charge, err
+	}
+	charge = latestCopy(payments, charge)
+	if charge.Status != "success" {
+		return models.Payment{}, charge, errors.New("payment has already been fully refunded")
+	}
+
+	amount, err := refundAmount(charge, payments, requested)
+	if err != nil {
+		return models.Payment{}, charge, err
+	}
+
+	refund := models.Payment{
+		ID:        primitive.NewObjectID(),
+		OrderID:   charge.OrderID,
+		UserID:    charge.UserID,
+		Type:      models.PaymentTypeRefund,
+		RefundOf:  charge.ID.Hex(),
+		Amount:    amount,
+		Method:    charge.Method,
+		Status:    "pending",
+		Email:     charge.Email,
+		Reference: primitive.NewObjectID().Hex(),
+		Reason:    reason,
+	}
+
+	if toWallet || charge.Method == "wallet" {
+		if toWallet {
+			if _, err := r.walletRepo.EnsureWallet(sc, charge.UserID, amount.Currency); err != nil {
+				return models.Payment{}, charge, err
+			}
+		}
+		if _, err := r.walletRepo.IncreaseBalance(sc, charge.UserID, amount); err != nil {
+			return models.Payment{}, charge, err
+		}
+		refund.Method = "wallet"
+		refund.Status = "success"
+	}
+
+	if refund, err = r.paymentRepo.Create(sc, refund); err != nil {
+		return models.Payment{}, charge, err
+	}
+	if err := r.settler.applyRefunds(sc, charge, append(payments, refund)); err != nil {
+		return models.Payment{}, charge, err
+	}
+	return refund, charge, nil
+}
+
+// send asks the provider to pay out a card refund recorded by reserve, once its
+// transaction has committed. A refund the provider turns down is released again.
+func (r paymentRefunder) send(ctx context.Context, charge models.Payment, refund models.Payment, note string) error {
+	if refund.Status != "pending" {
+		return nil
+	}
+
+	err := r.provider.Refund(ctx, models.RefundRequest{
+		Reference: charge.Reference,
+		Amount:    refund.Amount,
+		Note:      note,
+	})
+	if err == nil {
+		return nil
+	}
+
+	// A timed out request may still have reached the provider; the refund stays
+	// pending and the provider's webhook settles it either way.
+	var providerErr *services.ProviderError
+	if errors.As(err, &providerErr) && providerErr.Timeout {
+		log.Printf("refund %s of payment %s timed out; awaiting the provider", refund.Reference, charge.Reference)
+		return nil
+	}
+
+	if releaseErr := r.release(ctx, charge, refund); releaseErr != nil {
+		log.Printf("failed to release refund %s: %v", refund.Reference, releaseErr)
+	}
+	return err
+}
+
+// release marks a refund the provider turned down as failed, giving its amount back
+// to the charge.
+func (r paymentRefunder) release(ctx context.Context, charge models.Payment, refund models.Payment) error {
+	return repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		changed, err := r.paymentRepo.UpdateStatusFrom(sc, refund.ID, []string{"pending"}, "failed")
+		if err != nil || !changed {
+			return err
+		}
+
+		payments, err := r.paymentRepo.FindByOrderID(sc, charge.OrderID)
+		if err != nil {
+			return err
+		}
+		return r.settler.applyRefunds(sc, latestCopy(payments, charge), payments)
+	})
+}
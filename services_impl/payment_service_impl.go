@@ -3,92 +3,706 @@ package services_impl
 import (
 	"adhomes-backend/models"
 	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+	"adhomes-backend/utils"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type paymentServiceImpl struct {
 	paymentRepo *repositories.PaymentRepository
 	orderRepo   *repositories.OrderRepository
 	walletRepo  *repositories.WalletRepository
+	reportRepo  *repositories.ReconciliationReportRepository
+	provider    services.PaymentProvider
+	settler     paymentSettler
+	refunder    paymentRefunder
 }
 
 // NewPaymentService creates a new PaymentService
-func NewPaymentService(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository, walletRepo *repositories.WalletRepository) *paymentServiceImpl {
+func NewPaymentService(
+	paymentRepo *repositories.PaymentRepository,
+	orderRepo *repositories.OrderRepository,
+	walletRepo *repositories.WalletRepository,
+	reportRepo *repositories.ReconciliationReportRepository,
+	provider services.PaymentProvider,
+) *paymentServiceImpl {
 	return &paymentServiceImpl{
 		paymentRepo: paymentRepo,
 		orderRepo:   orderRepo,
 		walletRepo:  walletRepo,
+		reportRepo:  reportRepo,
+		provider:    provider,
+		settler:     paymentSettler{paymentRepo: paymentRepo, orderRepo: orderRepo, walletRepo: walletRepo},
+		refunder:    newPaymentRefunder(paymentRepo, orderRepo, walletRepo, provider),
 	}
 }
 
-// MakePayment handles wallet or paystack payment
+// MakePayment handles wallet, paystack or split payment
 func (s *paymentServiceImpl) MakePayment(req models.PaymentRequest) (models.Payment, string, error) {
 	ctx := context.Background()
 
 	// 1️⃣ Validate order
-	order, err := s.orderRepo.FindOrderByID(req.OrderID)
+	// Orders belonging to someone else are reported as not found
+	order, err := s.orderRepo.FindUserOrder(req.OrderID, req.UserID)
 	if err != nil {
 		return models.Payment{}, "", err
 	}
 
-	// Ensure the order belongs to the user
-	if order.CustomerEmail != req.UserID {
-		return models.Payment{}, "", errors.New("order does not belong to user")
+	if req.Amount.Currency != order.TotalAmount.Currency {
+		return models.Payment{}, "", errors.New("payment currency does not match order currency")
 	}
 
-	// Ensure the amount matches
+	// Ensure the amount matches; both are whole minor units, so this is exact
 	if order.TotalAmount != req.Amount {
 		return models.Payment{}, "", errors.New("amount does not match order total")
 	}
 
+	if order.IsPaid() {
+		return models.Payment{}, "", errors.New("order has already been paid")
+	}
+
+	// An open card payment, or wallet money held for one, may still pay the order
+	payments, err := s.paymentRepo.FindByOrderID(ctx, req.OrderID)
+	if err != nil {
+		return models.Payment{}, "", err
+	}
+	if paymentInProgress(payments) {
+		return models.Payment{}, "", errors.New("a payment for this order is already in progress")
+	}
+
+	// A cancelled or already fulfilled order cannot be paid
+	if !utils.IsPayableStatus(order.Status) {
+		return models.Payment{}, "", errors.New("order can no longer be paid")
+	}
+
 	// Create a new payment record
 	payment := models.Payment{
 		ID:        primitive.NewObjectID(),
 		UserID:    req.UserID,
 		OrderID:   req.OrderID,
 		Amount:    req.Amount,
+		Type:      models.PaymentTypeCharge,
 		Email:     req.Email,
 		Method:    req.PaymentMethod,
 		Status:    "pending",
 		Reference: primitive.NewObjectID().Hex(),
 	}
 
+	switch req.PaymentMethod {
 	// 2️⃣ Wallet payment
-	if req.PaymentMethod == "wallet" {
-		wallet, err := s.walletRepo.FindByUserID(ctx, req.UserID)
+	case "wallet":
+		wallet, err := s.walletRepo.EnsureWallet(ctx, req.UserID, models.DefaultCurrency)
 		if err != nil {
 			return models.Payment{}, "", err
 		}
 
-		if wallet.Balance < req.Amount {
+		// Wallets hold a single currency
+		if wallet.Balance.Currency != req.Amount.Currency {
+			return models.Payment{}, "", errors.New("wallet currency does not match order currency")
+		}
+
+		if wallet.Balance.Cmp(req.Amount) < 0 {
 			return models.Payment{}, "", errors.New("insufficient wallet balance")
 		}
 
-		_, err = s.walletRepo.DecreaseBalance(ctx, req.UserID, req.Amount)
-		if err != nil {
-			return models.Payment{}, "", err
+		p, err := s.payFromWallet(ctx, payment)
+		return p, "", err
+
+	// 3️⃣ Paystack payment
+	case "paystack":
+		return s.startPaystackPayment(ctx, payment)
+
+	// 4️⃣ Wallet balance first, card for the rest
+	case "split":
+		return s.startSplitPayment(ctx, payment)
+	}
+
+	return models.Payment{}, "", errors.New("invalid payment method")
+}
+
+// payFromWallet debits the wallet for the whole payment, records it and marks the
+// order paid, all in one transaction: either all of it happens or none of it does.
+// The debit only succeeds while the balance covers it, and the order is only marked
+// paid while nothing has been paid for it and it has not been cancelled, so neither
+// a concurrent payment nor a concurrent cancellation can leave the wallet debited.
+func (s *paymentServiceImpl) payFromWallet(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	payment.Method = "wallet"
+	payment.Status = "success"
+
+	err := repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := s.walletRepo.DecreaseBalance(sc, payment.UserID, payment.Amount); err != nil {
+			return err
 		}
 
-		payment.Status = "success"
-		if err := s.orderRepo.UpdateOrderStatus(req.OrderID, "paid"); err != nil {
-			return models.Payment{}, "", err
+		if err := s.orderRepo.MarkPaid(sc, payment.OrderID); err != nil {
+			return err
+		}
+
+		var err error
+		payment, err = s.paymentRepo.Create(sc, payment)
+		return err
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+	return payment, nil
+}
+
+// startSplitPayment pays what it can from the wallet and the rest by card. The wallet
+// part is held, not spent: it is recorded as a "held" payment linked to the pending
+// card payment, captured when the card payment succeeds and released back to the
+// wallet when it fails or stays pending too long. It returns the card payment.
+func (s *paymentServiceImpl) startSplitPayment(ctx context.Context, payment models.Payment) (models.Payment, string, error) {
+	var held models.Money
+	wallet, err := s.walletRepo.FindByUserID(ctx, payment.UserID)
+	switch {
+	case err == nil:
+		if wallet.Balance.Currency != payment.Amount.Currency {
+			return models.Payment{}, "", errors.New("wallet currency does not match order currency")
 		}
+		held = wallet.Balance.Min(payment.Amount)
+	case err.Error() != "wallet not found":
+		return models.Payment{}, "", err
+	}
 
-		// Save payment
-		p, err := s.paymentRepo.Create(payment)
+	// Nothing to split: one method covers it all
+	if held.IsZero() || held.IsNegative() {
+		payment.Method = "paystack"
+		return s.startPaystackPayment(ctx, payment)
+	}
+	if held == payment.Amount {
+		p, err := s.payFromWallet(ctx, payment)
 		return p, "", err
 	}
 
-	// 3️⃣ Paystack payment (mocked)
-	if req.PaymentMethod == "paystack" {
-		paymentURL := "https://paystack.com/pay/" + payment.Reference
+	walletLeg, cardLeg := splitLegs(payment, held)
 
-		p, err := s.paymentRepo.Create(payment)
-		return p, paymentURL, err
+	err = repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := s.walletRepo.DecreaseBalance(sc, walletLeg.UserID, walletLeg.Amount); err != nil {
+			return err
+		}
+		if _, err := s.paymentRepo.Create(sc, walletLeg); err != nil {
+			return err
+		}
+		var err error
+		cardLeg, err = s.paymentRepo.Create(sc, cardLeg)
+		return err
+	})
+	if err != nil {
+		return models.Payment{}, "", err
 	}
 
-	return models.Payment{}, "", errors.New("invalid payment method")
+	return s.openTransaction(ctx, cardLeg)
+}
+
+// paymentInProgress reports whether a charge among payments is still waiting on its outcome.
+func paymentInProgress(payments []models.Payment) bool {
+	for _, p := range payments {
+		if p.Type != models.PaymentTypeRefund && (p.Status == "pending" || p.Status == "held") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLegs divides payment into a held wallet part of held and a pending card part
+// for the rest, each linked to the other.
+func splitLegs(payment models.Payment, held models.Money) (models.Payment, models.Payment) {
+	walletLeg := payment
+	walletLeg.ID = primitive.NewObjectID()
+	walletLeg.Reference = primitive.NewObjectID().Hex()
+	walletLeg.Method = "wallet"
+	walletLeg.Amount = held
+	walletLeg.Status = "held"
+
+	cardLeg := payment
+	cardLeg.ID = primitive.NewObjectID()
+	cardLeg.Reference = primitive.NewObjectID().Hex()
+	cardLeg.Method = paystackProviderName
+	cardLeg.Amount = payment.Amount.Sub(held)
+	cardLeg.Status = "pending"
+
+	walletLeg.SplitWith = cardLeg.ID.Hex()
+	cardLeg.SplitWith = walletLeg.ID.Hex()
+	return walletLeg, cardLeg
+}
+
+// StartGuestPayment opens a Paystack payment for the full total of a guest order.
+// Guests have no wallet, so the card is the only option.
+func (s *paymentServiceImpl) StartGuestPayment(order models.Order) (models.Payment, string, error) {
+	if !utils.IsPayableStatus(order.Status) {
+		return models.Payment{}, "", errors.New("order can no longer be paid")
+	}
+
+	payment := models.Payment{
+		ID:        primitive.NewObjectID(),
+		UserID:    order.UserID,
+		OrderID:   order.ID.Hex(),
+		Amount:    order.TotalAmount,
+		Type:      models.PaymentTypeCharge,
+		Email:     order.CustomerEmail,
+		Method:    "paystack",
+		Status:    "pending",
+		Reference: primitive.NewObjectID().Hex(),
+	}
+
+	return s.startPaystackPayment(context.Background(), payment)
+}
+
+// startPaystackPayment records a pending card payment and opens the matching
+// transaction with the provider. The payment is saved first so a provider callback
+// always finds it.
+func (s *paymentServiceImpl) startPaystackPayment(ctx context.Context, payment models.Payment) (models.Payment, string, error) {
+	payment, err := s.paymentRepo.Create(ctx, payment)
+	if err != nil {
+		return models.Payment{}, "", err
+	}
+	return s.openTransaction(ctx, payment)
+}
+
+// openTransaction opens the provider transaction for a saved, pending card payment.
+// If the provider fails, the payment is marked failed.
+func (s *paymentServiceImpl) openTransaction(ctx context.Context, payment models.Payment) (models.Payment, string, error) {
+	init, err := s.provider.InitializeTransaction(ctx, models.TransactionRequest{
+		Reference: payment.Reference,
+		Email:     payment.Email,
+		Amount:    payment.Amount,
+		Metadata:  transactionMetadata(payment),
+	})
+	if err != nil {
+		if _, updateErr := s.settler.failCharge(ctx, payment); updateErr != nil {
+			log.Printf("failed to mark payment %s failed: %v", payment.Reference, updateErr)
+		}
+		return models.Payment{}, "", err
+	}
+
+	if err := s.paymentRepo.SetAuthorization(ctx, payment.ID, init.AuthorizationURL, init.AccessCode); err != nil {
+		return models.Payment{}, "", err
+	}
+	payment.AuthorizationURL = init.AuthorizationURL
+	payment.AccessCode = init.AccessCode
+
+	return payment, init.AuthorizationURL, nil
+}
+
+// transactionMetadata tells whoever looks at the transaction on the provider's side what it paid for.
+func transactionMetadata(payment models.Payment) map[string]string {
+	if payment.Type == models.PaymentTypeTopUp {
+		return map[string]string{"wallet_topup": payment.UserID}
+	}
+	return map[string]string{"order_id": payment.OrderID}
+}
+
+// -----------------------------
+// WALLET TOP-UP
+// -----------------------------
+
+// StartWalletTopUp opens a card payment into userID's wallet. The wallet is credited
+// once the provider confirms the payment, by webhook or verification.
+func (s *paymentServiceImpl) StartWalletTopUp(userID string, amount models.Money) (models.Payment, string, error) {
+	ctx := context.Background()
+
+	if amount.IsZero() || amount.IsNegative() {
+		return models.Payment{}, "", errors.New("top-up amount must be greater than zero")
+	}
+
+	wallet, err := s.walletRepo.EnsureWallet(ctx, userID, models.DefaultCurrency)
+	if err != nil {
+		return models.Payment{}, "", err
+	}
+	// Wallets hold a single currency
+	if wallet.Balance.Currency != amount.Currency {
+		return models.Payment{}, "", errors.New("top-up currency does not match wallet currency")
+	}
+
+	payment := models.Payment{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Type:      models.PaymentTypeTopUp,
+		Amount:    amount,
+		Email:     userID,
+		Method:    paystackProviderName,
+		Status:    "pending",
+		Reference: primitive.NewObjectID().Hex(),
+	}
+
+	return s.startPaystackPayment(ctx, payment)
+}
+
+// -----------------------------
+// VERIFICATION
+// -----------------------------
+
+// VerifyPayment asks the provider about the payment with reference and brings our
+// payment and order records in line with its answer.
+func (s *paymentServiceImpl) VerifyPayment(reference string) (models.Payment, error) {
+	ctx := context.Background()
+
+	payment, err := s.paymentRepo.FindByReference(ctx, reference)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	if _, err := s.reconcile(ctx, payment, 0); err != nil {
+		return models.Payment{}, err
+	}
+	return s.paymentRepo.FindByReference(ctx, reference)
+}
+
+// VerifyUserPayment is VerifyPayment for a payment userID made.
+func (s *paymentServiceImpl) VerifyUserPayment(reference string, userID string) (models.Payment, error) {
+	payment, err := s.paymentRepo.FindByReference(context.Background(), reference)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if payment.UserID != userID {
+		return models.Payment{}, errors.New("payment not found")
+	}
+	return s.VerifyPayment(reference)
+}
+
+// reconcile verifies one gateway charge that has no final outcome yet, applies what
+// the provider reports, and describes any difference it found. Payments with nothing
+// to verify come back without a discrepancy.
+func (s *paymentServiceImpl) reconcile(ctx context.Context, payment models.Payment, holdTimeout time.Duration) (*models.PaymentDiscrepancy, error) {
+	if payment.Type == models.PaymentTypeRefund || payment.Method != paystackProviderName {
+		return nil, nil
+	}
+	if payment.Status != "pending" && payment.Status != "failed" {
+		return nil, nil
+	}
+
+	tx, err := s.provider.VerifyTransaction(ctx, payment.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancy := &models.PaymentDiscrepancy{
+		PaymentID:      payment.ID.Hex(),
+		Reference:      payment.Reference,
+		OrderID:        payment.OrderID,
+		LocalStatus:    payment.Status,
+		ProviderStatus: tx.Status,
+		LocalAmount:    payment.Amount,
+		ProviderAmount: &tx.Amount,
+	}
+
+	switch tx.Status {
+	case "success":
+		if tx.Amount != payment.Amount {
+			discrepancy.Kind = models.DiscrepancyAmountMismatch
+			return discrepancy, nil
+		}
+
+		settled, note, err := s.settler.settleCharge(ctx, payment)
+		if err != nil {
+			return nil, err
+		}
+		if note != "" {
+			switch note {
+			case noteHoldReleased:
+				discrepancy.Kind = models.DiscrepancyUnderpaid
+			case noteAlreadyPaid:
+				discrepancy.Kind = models.DiscrepancyDoublePayment
+			default:
+				discrepancy.Kind = models.DiscrepancyPaidAfterCancel
+			}
+			discrepancy.Note = note
+			return discrepancy, nil
+		}
+		if !settled {
+			return nil, nil
+		}
+		discrepancy.Kind = models.DiscrepancyMissedSuccess
+		discrepancy.Resolved = true
+		return discrepancy, nil
+
+	case "failed", "reversed":
+		failed, err := s.settler.failCharge(ctx, payment)
+		if err != nil || !failed {
+			return nil, err
+		}
+		discrepancy.Kind = models.DiscrepancyMissedFailure
+		discrepancy.Resolved = true
+		return discrepancy, nil
+	}
+
+	// Abandoned, ongoing or otherwise undecided at the provider
+	if payment.Status == "failed" {
+		return nil, nil
+	}
+
+	// The wallet part of a split payment is not held forever for a card payment that may never come
+	if payment.SplitWith != "" && holdTimeout > 0 && time.Since(payment.CreatedAt) > holdTimeout {
+		failed, err := s.settler.failCharge(ctx, payment)
+		if err != nil || !failed {
+			return nil, err
+		}
+		discrepancy.Kind = models.DiscrepancyHoldExpired
+		discrepancy.Resolved = true
+		return discrepancy, nil
+	}
+
+	discrepancy.Kind = models.DiscrepancyUnresolved
+	return discrepancy, nil
+}
+
+// -----------------------------
+// RECONCILIATION (BACKGROUND)
+// -----------------------------
+
+const (
+	// reconciliationBatchSize bounds the payments verified per run; later runs pick up the rest.
+	reconciliationBatchSize = 100
+	// reconciliationReportLimit caps how many past reports admins are shown.
+	reconciliationReportLimit = 50
+)
+
+// ReconcilePendingPayments re-verifies card payments that have stayed pending for longer
+// than olderThan, and releases split payment wallet holds older than holdTimeout.
+// A report is saved whenever there was anything to check.
+func (s *paymentServiceImpl) ReconcilePendingPayments(ctx context.Context, olderThan time.Duration, holdTimeout time.Duration) (models.ReconciliationReport, error) {
+	report := models.ReconciliationReport{
+		StartedAt:     time.Now(),
+		Discrepancies: []models.PaymentDiscrepancy{},
+	}
+
+	payments, err := s.paymentRepo.FindPendingBefore(ctx, paystackProviderName, report.StartedAt.Add(-olderThan), reconciliationBatchSize)
+	if err != nil {
+		return report, err
+	}
+
+	for _, payment := range payments {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Checked++
+
+		discrepancy, err := s.reconcile(ctx, payment, holdTimeout)
+		if err != nil {
+			discrepancy = &models.PaymentDiscrepancy{
+				PaymentID:   payment.ID.Hex(),
+				Reference:   payment.Reference,
+				OrderID:     payment.OrderID,
+				Kind:        models.DiscrepancyProviderError,
+				LocalStatus: payment.Status,
+				LocalAmount: payment.Amount,
+				Note:        err.Error(),
+			}
+		}
+		if discrepancy != nil {
+			report.Discrepancies = append(report.Discrepancies, *discrepancy)
+		}
+	}
+
+	if report.Checked == 0 {
+		return report, ctx.Err()
+	}
+
+	report.FinishedAt = time.Now()
+	if _, err := s.reportRepo.Create(context.Background(), report); err != nil {
+		return report, err
+	}
+	return report, ctx.Err()
+}
+
+func (s *paymentServiceImpl) GetReconciliationReports() ([]models.ReconciliationReport, error) {
+	return s.reportRepo.FindRecent(reconciliationReportLimit)
+}
+
+// -----------------------------
+// PAYMENT HISTORY
+// -----------------------------
+
+const (
+	defaultPaymentPageSize = 20
+	maxPaymentPageSize     = 100
+)
+
+func (s *paymentServiceImpl) GetUserPayments(userID string, query models.PaymentListQuery) ([]models.Payment, models.Pagination, error) {
+	query.UserID = userID
+	payments, pagination, _, err := s.listPayments(query, false)
+	return payments, pagination, err
+}
+
+func (s *paymentServiceImpl) GetOrderPayments(orderID string, userID string) ([]models.Payment, error) {
+	// Orders belonging to someone else are reported as not found
+	if _, err := s.orderRepo.FindUserOrder(orderID, userID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.paymentRepo.FindByOrderID(ctx, orderID)
+}
+
+func (s *paymentServiceImpl) GetAllPayments(query models.PaymentListQuery) ([]models.Payment, models.Pagination, []models.PaymentTotal, error) {
+	return s.listPayments(query, true)
+}
+
+// listPayments returns one page of the payments matching query and, when withTotals
+// is set, the per-method totals of everything that matches.
+func (s *paymentServiceImpl) listPayments(query models.PaymentListQuery, withTotals bool) ([]models.Payment, models.Pagination, []models.PaymentTotal, error) {
+	filter, err := buildPaymentFilter(query)
+	if err != nil {
+		return nil, models.Pagination{}, nil, err
+	}
+
+	page, limit := query.Page, query.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPaymentPageSize
+	}
+	if limit > maxPaymentPageSize {
+		limit = maxPaymentPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	payments, total, err := s.paymentRepo.FindPage(ctx, filter, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		return nil, models.Pagination{}, nil, err
+	}
+
+	var totals []models.PaymentTotal
+	if withTotals {
+		if totals, err = s.paymentRepo.TotalsByMethod(ctx, filter); err != nil {
+			return nil, models.Pagination{}, nil, err
+		}
+	}
+
+	return payments, models.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	}, totals, nil
+}
+
+func buildPaymentFilter(query models.PaymentListQuery) (bson.M, error) {
+	filter := bson.M{}
+
+	fields := map[string]string{
+		"status":   query.Status,
+		"method":   query.Method,
+		"type":     query.Type,
+		"order_id": query.OrderID,
+		"user_id":  query.UserID,
+	}
+	for field, value := range fields {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+
+	createdAt := bson.M{}
+	if query.From != "" {
+		from, err := parseDate(query.From, false)
+		if err != nil {
+			return nil, errors.New("invalid from date")
+		}
+		createdAt["$gte"] = from
+	}
+	if query.To != "" {
+		to, err := parseDate(query.To, true)
+		if err != nil {
+			return nil, errors.New("invalid to date")
+		}
+		createdAt["$lte"] = to
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return filter, nil
+}
+
+// -----------------------------
+// REFUNDS
+// -----------------------------
+
+func (s *paymentServiceImpl) RefundPayment(id string, req models.RefundPaymentRequest, actor string) (models.Payment, error) {
+	ctx := context.Background()
+
+	charge, err := s.paymentRepo.FindByID(ctx, id)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	switch charge.Type {
+	case models.PaymentTypeRefund:
+		return models.Payment{}, errors.New("refunds cannot be refunded")
+	case models.PaymentTypeTopUp:
+		return models.Payment{}, errors.New("wallet top-ups cannot be refunded")
+	}
+	switch charge.Status {
+	case "success":
+	case "refunded":
+		return models.Payment{}, errors.New("payment has already been fully refunded")
+	default:
+		return models.Payment{}, errors.New("only successful payments can be refunded")
+	}
+
+	reason := "refunded by " + actor
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	var refund models.Payment
+	err = repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		refund, charge, err = s.refunder.reserve(sc, charge, req.Amount, reason, false)
+		return err
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	if err := s.refunder.send(ctx, charge, refund, req.Reason); err != nil {
+		return models.Payment{}, err
+	}
+	return refund, nil
+}
+
+// latestCopy returns payment as it appears in payments, which were read after it.
+func latestCopy(payments []models.Payment, payment models.Payment) models.Payment {
+	for _, p := range payments {
+		if p.ID == payment.ID {
+			return p
+		}
+	}
+	return payment
+}
+
+// refundAmount works out how much to refund of charge: requested, or all that is
+// left of it when requested is nil. It never exceeds what is left.
+func refundAmount(charge models.Payment, payments []models.Payment, requested *models.Money) (models.Money, error) {
+	remaining := charge.Amount.Sub(refundedAmount(payments, charge.ID.Hex()))
+	if remaining.IsZero() || remaining.IsNegative() {
+		return models.Money{}, errors.New("payment has already been fully refunded")
+	}
+	if requested == nil {
+		return remaining, nil
+	}
+
+	if requested.Currency != charge.Amount.Currency {
+		return models.Money{}, errors.New("refund currency does not match payment currency")
+	}
+	if requested.IsZero() || requested.IsNegative() {
+		return models.Money{}, errors.New("refund amount must be positive")
+	}
+	if requested.Cmp(remaining) > 0 {
+		return models.Money{}, fmt.Errorf("refund exceeds the %s left on the payment", remaining)
+	}
+	return *requested, nil
 }
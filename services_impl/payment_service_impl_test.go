@@ -0,0 +1,444 @@
+package services_impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// stubProvider answers verification with a fixed transaction, and records refunds,
+// failing them with refundErr when set.
+type stubProvider struct {
+	tx        models.TransactionStatus
+	calls     int
+	refunds   []models.RefundRequest
+	refundErr error
+}
+
+func (p *stubProvider) InitializeTransaction(ctx context.Context, req models.TransactionRequest) (models.TransactionInit, error) {
+	return models.TransactionInit{}, nil
+}
+
+func (p *stubProvider) VerifyTransaction(ctx context.Context, reference string) (models.TransactionStatus, error) {
+	p.calls++
+	return p.tx, nil
+}
+
+func (p *stubProvider) Refund(ctx context.Context, req models.RefundRequest) error {
+	p.refunds = append(p.refunds, req)
+	return p.refundErr
+}
+
+func (p *stubProvider) VerifyWebhook(body []byte, signature string) bool { return false }
+
+func (p *stubProvider) ParseWebhook(body []byte) (models.PaymentEvent, error) {
+	return models.PaymentEvent{}, nil
+}
+
+func TestReconcileSkipsPaymentsWithNothingToVerify(t *testing.T) {
+	provider := &stubProvider{}
+	s := NewPaymentService(nil, nil, nil, nil, provider)
+
+	for _, payment := range []models.Payment{
+		{Method: "wallet", Status: "pending"},
+		{Method: "paystack", Type: models.PaymentTypeRefund, Status: "pending"},
+		{Method: "paystack", Status: "success"},
+	} {
+		discrepancy, err := s.reconcile(context.Background(), payment, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, discrepancy)
+	}
+	assert.Equal(t, 0, provider.calls)
+}
+
+func TestReconcileReportsUnsettledOutcomes(t *testing.T) {
+	payment := models.Payment{Reference: "ref-1", Method: "paystack", Status: "pending", Amount: ngn(500000)}
+
+	provider := &stubProvider{tx: models.TransactionStatus{Reference: "ref-1", Status: "abandoned", Amount: ngn(500000)}}
+	s := NewPaymentService(nil, nil, nil, nil, provider)
+	discrepancy, err := s.reconcile(context.Background(), payment, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DiscrepancyUnresolved, discrepancy.Kind)
+	assert.False(t, discrepancy.Resolved)
+
+	provider.tx = models.TransactionStatus{Reference: "ref-1", Status: "success", Amount: ngn(400000)}
+	discrepancy, err = s.reconcile(context.Background(), payment, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DiscrepancyAmountMismatch, discrepancy.Kind)
+	assert.Equal(t, ngn(400000), *discrepancy.ProviderAmount)
+}
+
+func TestBuildPaymentFilter(t *testing.T) {
+	filter, err := buildPaymentFilter(models.PaymentListQuery{
+		Status: "success",
+		Method: "paystack",
+		UserID: "ada@example.com",
+		From:   "2025-03-01",
+		To:     "2025-03-31",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", filter["status"])
+	assert.Equal(t, "paystack", filter["method"])
+	assert.Equal(t, "ada@example.com", filter["user_id"])
+	assert.NotContains(t, filter, "order_id")
+
+	createdAt := filter["created_at"].(bson.M)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), createdAt["$gte"])
+	assert.Equal(t, time.Date(2025, 3, 31, 23, 59, 59, 999999999, time.UTC), createdAt["$lte"])
+
+	_, err = buildPaymentFilter(models.PaymentListQuery{To: "31/03/2025"})
+	assert.EqualError(t, err, "invalid to date")
+}
+
+func TestRefundAmount(t *testing.T) {
+	charge := models.Payment{ID: primitive.NewObjectID(), Amount: ngn(1000000), Status: "success"}
+	refund := func(amount int64, status string) models.Payment {
+		return models.Payment{Type: models.PaymentTypeRefund, RefundOf: charge.ID.Hex(), Amount: ngn(amount), Status: status}
+	}
+	payments := []models.Payment{charge, refund(300000, "success"), refund(200000, "pending"), refund(500000, "failed")}
+
+	amount, err := refundAmount(charge, payments, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(500000), amount)
+
+	requested := ngn(500000)
+	amount, err = refundAmount(charge, payments, &requested)
+	assert.NoError(t, err)
+	assert.Equal(t, ngn(500000), amount)
+
+	requested = ngn(500001)
+	_, err = refundAmount(charge, payments, &requested)
+	assert.EqualError(t, err, "refund exceeds the NGN 5,000.00 left on the payment")
+
+	requested = ngn(0)
+	_, err = refundAmount(charge, payments, &requested)
+	assert.EqualError(t, err, "refund amount must be positive")
+
+	requested = models.NewMoney(100, "USD")
+	_, err = refundAmount(charge, payments, &requested)
+	assert.EqualError(t, err, "refund currency does not match payment currency")
+
+	_, err = refundAmount(charge, append(payments, refund(500000, "pending")), nil)
+	assert.EqualError(t, err, "payment has already been fully refunded")
+}
+
+func TestSplitLegs(t *testing.T) {
+	payment := models.Payment{
+		OrderID: "order-1",
+		UserID:  "ada@example.com",
+		Type:    models.PaymentTypeCharge,
+		Amount:  ngn(1500000),
+		Method:  "split",
+		Status:  "pending",
+	}
+
+	walletLeg, cardLeg := splitLegs(payment, ngn(400000))
+
+	assert.Equal(t, "wallet", walletLeg.Method)
+	assert.Equal(t, "held", walletLeg.Status)
+	assert.Equal(t, ngn(400000), walletLeg.Amount)
+
+	assert.Equal(t, paystackProviderName, cardLeg.Method)
+	assert.Equal(t, "pending", cardLeg.Status)
+	assert.Equal(t, ngn(1100000), cardLeg.Amount)
+
+	assert.Equal(t, cardLeg.ID.Hex(), walletLeg.SplitWith)
+	assert.Equal(t, walletLeg.ID.Hex(), cardLeg.SplitWith)
+	assert.NotEqual(t, walletLeg.Reference, cardLeg.Reference)
+	assert.Equal(t, "order-1", walletLeg.OrderID)
+	assert.Equal(t, "order-1", cardLeg.OrderID)
+}
+
+func TestTransactionMetadata(t *testing.T) {
+	assert.Equal(t, map[string]string{"order_id": "order-1"},
+		transactionMetadata(models.Payment{Type: models.PaymentTypeCharge, OrderID: "order-1"}))
+	assert.Equal(t, map[string]string{"wallet_topup": "ada@example.com"},
+		transactionMetadata(models.Payment{Type: models.PaymentTypeTopUp, UserID: "ada@example.com"}))
+}
+
+func TestStartWalletTopUpRejectsEmptyAmounts(t *testing.T) {
+	provider := &stubProvider{}
+	s := NewPaymentService(nil, nil, nil, nil, provider)
+
+	for _, amount := range []models.Money{ngn(0), ngn(-500)} {
+		_, _, err := s.StartWalletTopUp("ada@example.com", amount)
+		assert.EqualError(t, err, "top-up amount must be greater than zero")
+	}
+}
+
+func newMockPaymentService(mt *mtest.T, provider *stubProvider) *paymentServiceImpl {
+	return NewPaymentService(
+		repositories.NewPaymentRepository(mt.DB.Collection("payments")),
+		repositories.NewOrderRepository(mt.DB.Collection("orders")),
+		repositories.NewWalletRepository(),
+		repositories.NewReconciliationReportRepository(mt.DB.Collection("reconciliation_reports")),
+		provider,
+	)
+}
+
+func unpaidOrder() models.Order {
+	return models.Order{
+		ID:            primitive.NewObjectID(),
+		UserID:        "ada@example.com",
+		Status:        utils.OrderStatusPending,
+		PaymentStatus: "unpaid",
+		TotalAmount:   ngn(500000),
+	}
+}
+
+func paymentRequest(order models.Order, method string) models.PaymentRequest {
+	return models.PaymentRequest{
+		UserID:        order.UserID,
+		OrderID:       order.ID.Hex(),
+		Amount:        order.TotalAmount,
+		Email:         order.UserID,
+		PaymentMethod: method,
+	}
+}
+
+func TestMakePaymentFromWalletOnlyMarksTheOrderPaid(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		order := unpaidOrder()
+		wallet := mockDoc(t, models.Wallet{UserID: order.UserID, Balance: ngn(800000)})
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			findReply(), // payments
+			findAndModifyReply(wallet),
+			findAndModifyReply(wallet), // debit
+			updateReply(1),             // paid
+			insertReply(),
+			okReply(),
+		)
+
+		payment, _, err := s.MakePayment(paymentRequest(order, "wallet"))
+		require.NoError(t, err)
+		assert.Equal(t, "success", payment.Status)
+		assert.Equal(t, []string{"find", "find", "findAndModify", "findAndModify", "update", "insert", "commitTransaction"}, sentCommands(mt))
+
+		// The guarded update is the only change to the order, and it leaves the status alone
+		filter, update := sentUpdate(t, mt, 0)
+		_, err = filter.LookupErr("payment_status", "$nin")
+		assert.NoError(t, err)
+		assert.Equal(t, "paid", update.Lookup("$set", "payment_status").StringValue())
+		_, err = update.LookupErr("$set", "status")
+		assert.Error(t, err)
+	})
+}
+
+func TestMakePaymentFromWalletRollsBackWhenTheOrderIsCancelledMeanwhile(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		order := unpaidOrder()
+		cancelled := order
+		cancelled.Status = utils.OrderStatusCancelled
+		wallet := mockDoc(t, models.Wallet{UserID: order.UserID, Balance: ngn(800000)})
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			findReply(), // payments
+			findAndModifyReply(wallet),
+			findAndModifyReply(wallet), // debit
+			updateReply(0),             // cancelled since it was read
+			findReply(mockDoc(t, cancelled)),
+			okReply(), // abortTransaction
+		)
+
+		_, _, err := s.MakePayment(paymentRequest(order, "wallet"))
+		assert.EqualError(t, err, "order has been cancelled")
+
+		// The debit is undone with the transaction and no payment is recorded
+		assert.Equal(t, []string{"find", "find", "findAndModify", "findAndModify", "update", "find", "abortTransaction"}, sentCommands(mt))
+		filter, _ := sentUpdate(t, mt, 0)
+		assert.Equal(t, utils.OrderStatusCancelled, filter.Lookup("status", "$ne").StringValue())
+	})
+}
+
+func TestMakePaymentRefusesWhileAnotherPaymentIsOpen(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		order := unpaidOrder()
+		held := models.Payment{ID: primitive.NewObjectID(), OrderID: order.ID.Hex(), Type: models.PaymentTypeCharge, Method: "wallet", Status: "held", Amount: ngn(200000)}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			findReply(mockDoc(t, held)),
+		)
+
+		_, _, err := s.MakePayment(paymentRequest(order, "wallet"))
+		assert.EqualError(t, err, "a payment for this order is already in progress")
+		assert.Equal(t, []string{"find", "find"}, sentCommands(mt))
+	})
+}
+
+func TestMakePaymentSplitHoldsTheWalletPart(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		order := unpaidOrder()
+		wallet := mockDoc(t, models.Wallet{UserID: order.UserID, Balance: ngn(200000)})
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, order)),
+			findReply(), // payments
+			findReply(wallet),
+			findAndModifyReply(wallet), // debit
+			insertReply(),              // wallet leg
+			insertReply(),              // card leg
+			okReply(),
+			updateReply(1), // authorization
+		)
+
+		cardLeg, _, err := s.MakePayment(paymentRequest(order, "split"))
+		require.NoError(t, err)
+		assert.Equal(t, "pending", cardLeg.Status)
+		assert.Equal(t, ngn(300000), cardLeg.Amount)
+
+		debit := sentCommand(t, mt, "findAndModify", 0).Command
+		assert.EqualValues(t, 200000, debit.Lookup("query", "balance.amount", "$gte").AsInt64())
+
+		walletLeg := sentCommand(t, mt, "insert", 0).Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "held", walletLeg.Lookup("status").StringValue())
+		assert.Equal(t, cardLeg.ID.Hex(), walletLeg.Lookup("split_with").StringValue())
+
+		// Nothing is paid until the card part succeeds: the only update opens its checkout
+		assert.Equal(t, []string{"find", "find", "find", "findAndModify", "insert", "insert", "commitTransaction", "update"}, sentCommands(mt))
+		filter, _ := sentUpdate(t, mt, 0)
+		assert.Equal(t, cardLeg.ID, filter.Lookup("_id").ObjectID())
+	})
+}
+
+func TestSettleChargeCapturesTheSplitHold(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		order := unpaidOrder()
+		cardLeg := models.Payment{ID: primitive.NewObjectID(), OrderID: order.ID.Hex(), Type: models.PaymentTypeCharge, Method: "paystack", Status: "pending", SplitWith: primitive.NewObjectID().Hex()}
+
+		mt.AddMockResponses(
+			updateReply(1), // card leg
+			findReply(mockDoc(t, order)),
+			updateReply(1), // hold captured
+			updateReply(1), // paid
+			okReply(),
+		)
+
+		settled, note, err := s.settler.settleCharge(context.Background(), cardLeg)
+		require.NoError(t, err)
+		assert.True(t, settled)
+		assert.Empty(t, note)
+
+		filter, update := sentUpdate(t, mt, 1)
+		assert.Equal(t, cardLeg.SplitWith, filter.Lookup("_id").ObjectID().Hex())
+		assert.Equal(t, "success", update.Lookup("$set", "status").StringValue())
+		_, update = sentUpdate(t, mt, 2)
+		assert.Equal(t, "paid", update.Lookup("$set", "payment_status").StringValue())
+	})
+}
+
+func TestFailChargeReleasesTheSplitHold(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockPaymentService(mt, &stubProvider{})
+		walletLeg := models.Payment{ID: primitive.NewObjectID(), UserID: "ada@example.com", Type: models.PaymentTypeCharge, Method: "wallet", Status: "held", Amount: ngn(200000)}
+		cardLeg := models.Payment{ID: primitive.NewObjectID(), Type: models.PaymentTypeCharge, Method: "paystack", Status: "pending", SplitWith: walletLeg.ID.Hex()}
+
+		mt.AddMockResponses(
+			updateReply(1), // card leg failed
+			findReply(mockDoc(t, walletLeg)),
+			updateReply(1), // hold released
+			findAndModifyReply(mockDoc(t, models.Wallet{UserID: walletLeg.UserID, Balance: ngn(200000)})),
+			okReply(),
+		)
+
+		failed, err := s.settler.failCharge(context.Background(), cardLeg)
+		require.NoError(t, err)
+		assert.True(t, failed)
+
+		_, update := sentUpdate(t, mt, 1)
+		assert.Equal(t, "released", update.Lookup("$set", "status").StringValue())
+		credit := sentCommand(t, mt, "findAndModify", 0).Command
+		assert.EqualValues(t, 200000, credit.Lookup("update", "$inc", "balance.amount").AsInt64())
+	})
+}
+
+func TestReconcileReportsASecondPaymentForAPaidOrder(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		payment := models.Payment{ID: primitive.NewObjectID(), Reference: "ref-2", Method: "paystack", Status: "pending", Amount: ngn(500000)}
+		order := unpaidOrder()
+		order.PaymentStatus = "paid"
+		payment.OrderID = order.ID.Hex()
+
+		provider := &stubProvider{tx: models.TransactionStatus{Reference: "ref-2", Status: "success", Amount: ngn(500000)}}
+		s := newMockPaymentService(mt, provider)
+
+		mt.AddMockResponses(
+			updateReply(1), // charge settled
+			findReply(mockDoc(t, order)),
+			updateReply(0), // order was already paid
+			findReply(mockDoc(t, order)),
+			okReply(),
+		)
+
+		discrepancy, err := s.reconcile(context.Background(), payment, 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.DiscrepancyDoublePayment, discrepancy.Kind)
+		assert.Equal(t, noteAlreadyPaid, discrepancy.Note)
+		assert.Equal(t, []string{"update", "find", "update", "find", "commitTransaction"}, sentCommands(mt))
+	})
+}
+
+func TestSettleChargeNotesACancellationThatCommittedMeanwhile(t *testing.T) {
+	cancelled := models.Order{ID: primitive.NewObjectID(), Status: utils.OrderStatusCancelled}
+	cases := map[string]struct {
+		replies  []bson.D
+		commands []string
+	}{
+		// the cancellation committed before the settlement read the order
+		"seen": {
+			replies: []bson.D{
+				updateReply(1), // charge settled
+				findReply(mockDoc(t, cancelled)),
+				updateReply(1), // payment status
+				okReply(),
+			},
+			commands: []string{"update", "find", "update", "commitTransaction"},
+		},
+		// the cancellation committed after the settlement read the order
+		"missed": {
+			replies: []bson.D{
+				updateReply(1), // charge settled
+				findReply(mockDoc(t, unpaidOrder())),
+				updateReply(0), // not marked paid
+				findReply(mockDoc(t, cancelled)),
+				updateReply(1), // payment status
+				okReply(),
+			},
+			commands: []string{"update", "find", "update", "find", "update", "commitTransaction"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			withMockDB(t, func(mt *mtest.T) {
+				s := newMockPaymentService(mt, &stubProvider{})
+				charge := models.Payment{ID: primitive.NewObjectID(), OrderID: cancelled.ID.Hex(), Type: models.PaymentTypeCharge, Method: "paystack", Status: "pending"}
+				mt.AddMockResponses(c.replies...)
+
+				settled, note, err := s.settler.settleCharge(context.Background(), charge)
+				require.NoError(t, err)
+				assert.True(t, settled)
+				assert.Equal(t, noteCancelledBeforePayment, note)
+				assert.Equal(t, c.commands, sentCommands(mt))
+			})
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package services_impl
+
+import (
+	"context"
+	"errors"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Notes left on a settled charge whose money needs attention from staff.
+const (
+	noteCancelledBeforePayment = "order was cancelled before the payment arrived; refund required"
+	noteHoldReleased           = "wallet part of the split payment was released before the card payment arrived; order is underpaid"
+	noteAlreadyPaid            = "order had already been paid by another payment; refund required"
+)
+
+// paymentSettler applies the outcome of a gateway charge to the payment and its
+// order. Webhooks and verification both go through it, so whichever learns of the
+// outcome first records it and the other finds nothing left to do.
+type paymentSettler struct {
+	paymentRepo *repositories.PaymentRepository
+	orderRepo   *repositories.OrderRepository
+	walletRepo  *repositories.WalletRepository
+}
+
+// settleCharge marks the charge successful and the order paid. It reports whether
+// this call did so, and a note when the money needs attention from staff. Only the
+// order's payment status changes; its fulfilment status is left to the admins.
+func (s paymentSettler) settleCharge(ctx context.Context, payment models.Payment) (bool, string, error) {
+	if payment.Type == models.PaymentTypeTopUp {
+		settled, err := s.settleTopUp(ctx, payment)
+		return settled, "", err
+	}
+
+	var settled bool
+	var note string
+	err := repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		changed, err := s.paymentRepo.UpdateStatusFrom(sc, payment.ID, []string{"pending", "failed"}, "success")
+		if err != nil || !changed {
+			return err
+		}
+		settled = true
+
+		// Read the order inside the transaction, so that a cancellation committing
+		// alongside it is either seen here or conflicts with the writes below
+		order, err := s.orderRepo.FindOrder(sc, payment.OrderID)
+		if err != nil {
+			return err
+		}
+
+		// Money that arrives after the order was cancelled has to be refunded by hand
+		if utils.NormalizeStatus(order.Status) == utils.OrderStatusCancelled {
+			note = noteCancelledBeforePayment
+			return s.orderRepo.SetPaymentStatus(sc, payment.OrderID, "paid")
+		}
+
+		// The card part of a split payment only pays the order together with its wallet part
+		if payment.SplitWith != "" {
+			captured, err := s.captureHold(sc, payment.SplitWith)
+			if err != nil {
+				return err
+			}
+			if !captured {
+				note = noteHoldReleased
+				return s.orderRepo.SetPaymentStatus(sc, payment.OrderID, "partially_paid")
+			}
+		}
+
+		// Another payment may have paid the order in the meantime; keep this one's
+		// record of the money so it can be refunded
+		err = s.orderRepo.MarkPaid(sc, payment.OrderID)
+		if err == nil {
+			return nil
+		}
+		switch err.Error() {
+		case "order has already been paid":
+			note = noteAlreadyPaid
+			return nil
+		case "order has been cancelled":
+			note = noteCancelledBeforePayment
+			return s.orderRepo.SetPaymentStatus(sc, payment.OrderID, "paid")
+		}
+		return err
+	})
+	if err != nil {
+		return false, "", err
+	}
+	return settled, note, nil
+}
+
+// settleTopUp marks a wallet top-up successful and credits the wallet with it.
+// It reports whether this call did so.
+func (s paymentSettler) settleTopUp(ctx context.Context, payment models.Payment) (bool, error) {
+	var settled bool
+	err := repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		changed, err := s.paymentRepo.UpdateStatusFrom(sc, payment.ID, []string{"pending", "failed"}, "success")
+		if err != nil || !changed {
+			return err
+		}
+		settled = true
+
+		_, err = s.walletRepo.IncreaseBalance(sc, payment.UserID, payment.Amount)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return settled, nil
+}
+
+// failCharge marks a pending charge failed, and gives back the wallet part when it was
+// the card part of a split payment; the order stays unpaid so the customer can try
+// again. It reports whether this call did so.
+func (s paymentSettler) failCharge(ctx context.Context, payment models.Payment) (bool, error) {
+	if payment.SplitWith == "" {
+		return s.paymentRepo.UpdateStatusFrom(ctx, payment.ID, []string{"pending"}, "failed")
+	}
+
+	var failed bool
+	err := repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		changed, err := s.paymentRepo.UpdateStatusFrom(sc, payment.ID, []string{"pending"}, "failed")
+		if err != nil || !changed {
+			return err
+		}
+		failed = true
+
+		leg, err := s.paymentRepo.FindByID(sc, payment.SplitWith)
+		if err != nil {
+			return err
+		}
+		_, err = releaseWalletHold(sc, s.paymentRepo, s.walletRepo, leg)
+		return err
+	})
+	return failed, err
+}
+
+// captureHold turns the held wallet part of a split payment into a successful charge.
+// It reports false when the hold had already been released.
+func (s paymentSettler) captureHold(ctx context.Context, legID string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(legID)
+	if err != nil {
+		return false, errors.New("Invalid payment id")
+	}
+	return s.paymentRepo.UpdateStatusFrom(ctx, id, []string{"held"}, "success")
+}
+
+// releaseWalletHold credits a held wallet payment back to the wallet and marks it
+// released. It reports whether this call did so; a hold is only ever released once.
+func releaseWalletHold(ctx context.Context, paymentRepo *repositories.PaymentRepository, walletRepo *repositories.WalletRepository, leg models.Payment) (bool, error) {
+	changed, err := paymentRepo.UpdateStatusFrom(ctx, leg.ID, []string{"held"}, "released")
+	if err != nil || !changed {
+		return false, err
+	}
+	if _, err := walletRepo.IncreaseBalance(ctx, leg.UserID, leg.Amount); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyRefunds brings the charge status and the order's payment status in line with
+// the refunds among payments, which must include every refund of the charge.
+func (s paymentSettler) applyRefunds(ctx context.Context, charge models.Payment, payments []models.Payment) error {
+	chargeStatus, orderPaymentStatus := refundStatuses(charge, payments)
+	if status := orderRefundStatus(payments); status != "" {
+		orderPaymentStatus = status
+	}
+	if chargeStatus != charge.Status {
+		if err := s.paymentRepo.UpdateStatus(ctx, charge.ID, chargeStatus); err != nil {
+			return err
+		}
+	}
+	return s.orderRepo.SetPaymentStatus(ctx, charge.OrderID, orderPaymentStatus)
+}
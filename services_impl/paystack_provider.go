@@ -0,0 +1,227 @@
+package services_impl
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"adhomes-backend/config"
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+)
+
+const paystackProviderName = "paystack"
+
+type paystackProvider struct {
+	cfg    config.PaystackConfig
+	client *http.Client
+}
+
+func NewPaystackProvider(cfg config.PaystackConfig) *paystackProvider {
+	return &paystackProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// paystackResponse is the envelope every Paystack API response comes in.
+type paystackResponse struct {
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// InitializeTransaction calls POST /transaction/initialize. Amounts go to Paystack
+// in the currency's minor unit, which is what models.Money holds.
+func (p *paystackProvider) InitializeTransaction(ctx context.Context, req models.TransactionRequest) (models.TransactionInit, error) {
+	body := map[string]interface{}{
+		"email":     req.Email,
+		"amount":    req.Amount.Amount,
+		"currency":  req.Amount.Currency,
+		"reference": req.Reference,
+	}
+	if p.cfg.CallbackURL != "" {
+		body["callback_url"] = p.cfg.CallbackURL
+	}
+	if len(req.Metadata) > 0 {
+		body["metadata"] = req.Metadata
+	}
+
+	var data struct {
+		AuthorizationURL string `json:"authorization_url"`
+		AccessCode       string `json:"access_code"`
+		Reference        string `json:"reference"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/transaction/initialize", body, &data); err != nil {
+		return models.TransactionInit{}, err
+	}
+
+	if data.AuthorizationURL == "" {
+		return models.TransactionInit{}, p.fail("response has no authorization url")
+	}
+
+	return models.TransactionInit{
+		AuthorizationURL: data.AuthorizationURL,
+		AccessCode:       data.AccessCode,
+		Reference:        data.Reference,
+	}, nil
+}
+
+// VerifyTransaction calls GET /transaction/verify/:reference.
+func (p *paystackProvider) VerifyTransaction(ctx context.Context, reference string) (models.TransactionStatus, error) {
+	var data struct {
+		Reference string      `json:"reference"`
+		Status    string      `json:"status"`
+		Amount    json.Number `json:"amount"`
+		Currency  string      `json:"currency"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/transaction/verify/"+url.PathEscape(reference), nil, &data); err != nil {
+		return models.TransactionStatus{}, err
+	}
+
+	amount, err := strconv.ParseInt(data.Amount.String(), 10, 64)
+	if err != nil {
+		return models.TransactionStatus{}, p.fail("unreadable transaction amount")
+	}
+
+	return models.TransactionStatus{
+		Reference: data.Reference,
+		Status:    data.Status,
+		Amount:    models.NewMoney(amount, data.Currency),
+	}, nil
+}
+
+func (p *paystackProvider) Refund(ctx context.Context, req models.RefundRequest) error {
+	body := map[string]interface{}{
+		"transaction": req.Reference,
+		"amount":      req.Amount.Amount,
+		"currency":    req.Amount.Currency,
+	}
+	if req.Note != "" {
+		body["merchant_note"] = req.Note
+	}
+	return p.call(ctx, http.MethodPost, "/refund", body, nil)
+}
+
+// call sends a request to the Paystack API and decodes the data of a successful
+// response into out. Every failure comes back as a *services.ProviderError.
+func (p *paystackProvider) call(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	if p.cfg.SecretKey == "" {
+		return p.fail("secret key is not configured")
+	}
+
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.cfg.BaseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return &services.ProviderError{Provider: paystackProviderName, Timeout: true}
+		}
+		return p.fail("request failed: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	var envelope paystackResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return p.fail(fmt.Sprintf("unreadable response (HTTP %d)", resp.StatusCode))
+	}
+
+	if resp.StatusCode >= 300 || !envelope.Status {
+		message := envelope.Message
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return p.fail(message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return p.fail("unreadable response data")
+	}
+	return nil
+}
+
+func (p *paystackProvider) fail(message string) error {
+	return &services.ProviderError{Provider: paystackProviderName, Message: message}
+}
+
+// VerifyWebhook checks the x-paystack-signature header: the hex HMAC-SHA512 of the
+// raw body keyed with the secret key.
+func (p *paystackProvider) VerifyWebhook(body []byte, signature string) bool {
+	if p.cfg.SecretKey == "" || signature == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha512.New, []byte(p.cfg.SecretKey))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ParseWebhook reads charge and refund events. Refund events name the charge they
+// refund in transaction_reference.
+func (p *paystackProvider) ParseWebhook(body []byte) (models.PaymentEvent, error) {
+	var payload struct {
+		Event string `json:"event"`
+		Data  struct {
+			Reference            string      `json:"reference"`
+			TransactionReference string      `json:"transaction_reference"`
+			Amount               json.Number `json:"amount"`
+			Currency             string      `json:"currency"`
+		} `json:"data"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil || payload.Event == "" {
+		return models.PaymentEvent{}, errors.New("invalid webhook payload")
+	}
+
+	event := models.PaymentEvent{Type: payload.Event, Reference: payload.Data.Reference}
+	if strings.HasPrefix(payload.Event, "refund.") {
+		event.Reference = payload.Data.TransactionReference
+	}
+
+	if payload.Data.Amount != "" {
+		amount, err := strconv.ParseInt(payload.Data.Amount.String(), 10, 64)
+		if err != nil {
+			return models.PaymentEvent{}, errors.New("invalid webhook amount")
+		}
+		event.Amount = models.NewMoney(amount, payload.Data.Currency)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,173 @@
+package services_impl
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"adhomes-backend/config"
+	"adhomes-backend/models"
+	"adhomes-backend/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakePaystack(t *testing.T, handler http.HandlerFunc) (*paystackProvider, func()) {
+	server := httptest.NewServer(handler)
+	provider := NewPaystackProvider(config.PaystackConfig{
+		BaseURL:   server.URL,
+		SecretKey: "sk_test_secret",
+		Timeout:   200 * time.Millisecond,
+	})
+	return provider, server.Close
+}
+
+func TestPaystackInitializeTransaction(t *testing.T) {
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/transaction/initialize", r.URL.Path)
+		assert.Equal(t, "Bearer sk_test_secret", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "ada@example.com", body["email"])
+		assert.Equal(t, float64(1250050), body["amount"])
+		assert.Equal(t, "NGN", body["currency"])
+		assert.Equal(t, "ref-1", body["reference"])
+
+		w.Write([]byte(`{"status": true, "message": "Authorization URL created", "data": {
+			"authorization_url": "https://checkout.paystack.com/abc", "access_code": "abc", "reference": "ref-1"}}`))
+	})
+	defer closeServer()
+
+	init, err := provider.InitializeTransaction(context.Background(), models.TransactionRequest{
+		Reference: "ref-1",
+		Email:     "ada@example.com",
+		Amount:    ngn(1250050),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionInit{
+		AuthorizationURL: "https://checkout.paystack.com/abc",
+		AccessCode:       "abc",
+		Reference:        "ref-1",
+	}, init)
+}
+
+func TestPaystackInitializeTransactionReportsRejection(t *testing.T) {
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status": false, "message": "Duplicate Transaction Reference"}`))
+	})
+	defer closeServer()
+
+	_, err := provider.InitializeTransaction(context.Background(), models.TransactionRequest{Reference: "ref-1", Amount: ngn(100)})
+
+	var providerErr *services.ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.False(t, providerErr.Timeout)
+	assert.EqualError(t, err, "paystack: Duplicate Transaction Reference")
+}
+
+func TestPaystackInitializeTransactionTimesOut(t *testing.T) {
+	// Hold the response until the client has given up
+	release := make(chan struct{})
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer closeServer()
+	defer close(release)
+
+	_, err := provider.InitializeTransaction(context.Background(), models.TransactionRequest{Reference: "ref-1", Amount: ngn(100)})
+
+	var providerErr *services.ProviderError
+	assert.ErrorAs(t, err, &providerErr)
+	assert.True(t, providerErr.Timeout)
+}
+
+func TestPaystackInitializeTransactionHandlesServerErrors(t *testing.T) {
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`<html>bad gateway</html>`))
+	})
+	defer closeServer()
+
+	_, err := provider.InitializeTransaction(context.Background(), models.TransactionRequest{Reference: "ref-1", Amount: ngn(100)})
+	assert.EqualError(t, err, "paystack: unreadable response (HTTP 502)")
+}
+
+func TestPaystackRequiresSecretKey(t *testing.T) {
+	provider := NewPaystackProvider(config.PaystackConfig{BaseURL: "http://127.0.0.1:0"})
+
+	_, err := provider.InitializeTransaction(context.Background(), models.TransactionRequest{})
+	assert.EqualError(t, err, "paystack: secret key is not configured")
+}
+
+func TestPaystackVerifyWebhook(t *testing.T) {
+	provider := NewPaystackProvider(config.PaystackConfig{SecretKey: "sk_test_secret"})
+	body := []byte(`{"event":"charge.success","data":{"reference":"ref-1"}}`)
+
+	mac := hmac.New(sha512.New, []byte("sk_test_secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, provider.VerifyWebhook(body, signature))
+	assert.False(t, provider.VerifyWebhook([]byte(`{"event":"charge.success","data":{"reference":"ref-2"}}`), signature))
+	assert.False(t, provider.VerifyWebhook(body, "not-hex"))
+	assert.False(t, provider.VerifyWebhook(body, ""))
+}
+
+func TestPaystackParseWebhook(t *testing.T) {
+	provider := NewPaystackProvider(config.PaystackConfig{})
+
+	charge, err := provider.ParseWebhook([]byte(`{"event":"charge.success","data":{"reference":"ref-1","amount":1250050,"currency":"NGN"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentEvent{Type: "charge.success", Reference: "ref-1", Amount: ngn(1250050)}, charge)
+
+	// Refund events name the charge in transaction_reference and may send the amount as a string
+	refund, err := provider.ParseWebhook([]byte(`{"event":"refund.processed","data":{"transaction_reference":"ref-1","amount":"50000","currency":"NGN"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, models.PaymentEvent{Type: "refund.processed", Reference: "ref-1", Amount: ngn(50000)}, refund)
+
+	_, err = provider.ParseWebhook([]byte(`not json`))
+	assert.EqualError(t, err, "invalid webhook payload")
+}
+
+func TestPaystackVerifyTransaction(t *testing.T) {
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/transaction/verify/ref-1", r.URL.Path)
+
+		w.Write([]byte(`{"status": true, "message": "Verification successful", "data": {
+			"reference": "ref-1", "status": "abandoned", "amount": 1250050, "currency": "NGN"}}`))
+	})
+	defer closeServer()
+
+	tx, err := provider.VerifyTransaction(context.Background(), "ref-1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TransactionStatus{Reference: "ref-1", Status: "abandoned", Amount: ngn(1250050)}, tx)
+}
+
+func TestPaystackRefund(t *testing.T) {
+	provider, closeServer := fakePaystack(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/refund", r.URL.Path)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "ref-1", body["transaction"])
+		assert.Equal(t, float64(250000), body["amount"])
+		assert.Equal(t, "damaged in transit", body["merchant_note"])
+
+		w.Write([]byte(`{"status": true, "message": "Refund has been queued for processing", "data": {"status": "pending"}}`))
+	})
+	defer closeServer()
+
+	err := provider.Refund(context.Background(), models.RefundRequest{Reference: "ref-1", Amount: ngn(250000), Note: "damaged in transit"})
+	assert.NoError(t, err)
+}
@@ -12,11 +12,13 @@ import (
 
 type ProductServiceImpl struct {
 	productRepo *repositories.ProductRepository
+	rateRepo    *repositories.ExchangeRateRepository
 }
 
-func NewProductService(productRepo *repositories.ProductRepository) *ProductServiceImpl {
+func NewProductService(productRepo *repositories.ProductRepository, rateRepo *repositories.ExchangeRateRepository) *ProductServiceImpl {
 	return &ProductServiceImpl{
 		productRepo: productRepo,
+		rateRepo:    rateRepo,
 	}
 }
 
@@ -81,3 +83,62 @@ func (s *ProductServiceImpl) GetProductByID(id string) (*models.Product, error)
 	}
 	return s.productRepo.FindByID(objID)
 }
+
+// --------------------
+// PRICES IN ANOTHER CURRENCY
+// --------------------
+func (s *ProductServiceImpl) GetAllProductsIn(currency string) ([]models.Product, error) {
+	rates, currency, err := s.currencyRates(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.productRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range products {
+		if err := localizeProduct(&products[i], rates, currency); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func (s *ProductServiceImpl) GetProductByIDIn(id string, currency string) (*models.Product, error) {
+	rates, currency, err := s.currencyRates(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.GetProductByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := localizeProduct(product, rates, currency); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *ProductServiceImpl) currencyRates(currency string) (exchangeTable, string, error) {
+	rates, err := loadExchangeTable(s.rateRepo)
+	if err != nil {
+		return nil, "", err
+	}
+	currency, err = rates.resolve(currency)
+	return rates, currency, err
+}
+
+// localizeProduct replaces the product's price with its price in currency.
+func localizeProduct(product *models.Product, rates exchangeTable, currency string) error {
+	price, err := rates.productPrice(*product, currency)
+	if err != nil {
+		return err
+	}
+	product.Price = price
+	product.Prices = nil
+	return nil
+}
@@ -0,0 +1,213 @@
+package services_impl
+
+import (
+	"errors"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type reorderServiceImpl struct {
+	orderRepo    *repositories.OrderRepository
+	productRepo  *repositories.ProductRepository
+	cartRepo     *repositories.CartRepository
+	rateRepo     *repositories.ExchangeRateRepository
+	orderService services.OrderService
+}
+
+func NewReorderService(
+	orderRepo *repositories.OrderRepository,
+	productRepo *repositories.ProductRepository,
+	cartRepo *repositories.CartRepository,
+	rateRepo *repositories.ExchangeRateRepository,
+	orderService services.OrderService,
+) *reorderServiceImpl {
+	return &reorderServiceImpl{
+		orderRepo:    orderRepo,
+		productRepo:  productRepo,
+		cartRepo:     cartRepo,
+		rateRepo:     rateRepo,
+		orderService: orderService,
+	}
+}
+
+// Reorder carries the lines of an old order over at today's prices and stock.
+// Products no longer sold or out of stock are dropped, short stock lowers the
+// quantity, and every difference from the old order is reported.
+func (s *reorderServiceImpl) Reorder(id string, userID string, mode string) (models.ReorderResult, error) {
+	if mode == "" {
+		mode = models.ReorderModeOrder
+	}
+	if mode != models.ReorderModeOrder && mode != models.ReorderModeCart {
+		return models.ReorderResult{}, errors.New("invalid reorder mode")
+	}
+
+	previous, err := s.orderRepo.FindUserOrder(id, userID)
+	if err != nil {
+		return models.ReorderResult{}, err
+	}
+
+	rates, err := loadExchangeTable(s.rateRepo)
+	if err != nil {
+		return models.ReorderResult{}, err
+	}
+	currency, err := rates.resolve(previous.Currency)
+	if err != nil {
+		return models.ReorderResult{}, err
+	}
+
+	current := make(map[string]reorderCandidate, len(previous.Items))
+	for _, item := range previous.Items {
+		candidate, err := s.candidate(item.ProductID, rates, currency)
+		if err != nil {
+			return models.ReorderResult{}, err
+		}
+		current[item.ProductID] = candidate
+	}
+
+	items, changes := planReorder(previous.Items, current)
+	if len(items) == 0 {
+		return models.ReorderResult{}, errors.New("none of the items on this order are available")
+	}
+
+	result := models.ReorderResult{Mode: mode, Changes: changes}
+
+	if mode == models.ReorderModeCart {
+		cart, err := s.addToCart(userID, items)
+		if err != nil {
+			return models.ReorderResult{}, err
+		}
+		result.Cart = &cart
+		return result, nil
+	}
+
+	order, err := s.orderService.CreateOrder(models.Order{
+		UserID:          userID,
+		CustomerName:    previous.CustomerName,
+		CustomerEmail:   previous.CustomerEmail,
+		CustomerPhone:   previous.CustomerPhone,
+		DeliveryType:    previous.DeliveryType,
+		ShippingAddress: previous.ShippingAddress,
+		Currency:        currency,
+		Items:           items,
+	})
+	if err != nil {
+		return models.ReorderResult{}, err
+	}
+	result.Order = &order
+	return result, nil
+}
+
+// reorderCandidate is what the catalogue offers today for a product on an old order.
+// A nil product means it is no longer sold.
+type reorderCandidate struct {
+	product *models.Product
+	price   models.Money
+}
+
+func (s *reorderServiceImpl) candidate(productID string, rates exchangeTable, currency string) (reorderCandidate, error) {
+	oid, err := primitive.ObjectIDFromHex(productID)
+	if err != nil {
+		return reorderCandidate{}, nil
+	}
+
+	product, err := s.productRepo.FindByID(oid)
+	if err == mongo.ErrNoDocuments {
+		return reorderCandidate{}, nil
+	}
+	if err != nil {
+		return reorderCandidate{}, err
+	}
+
+	price, err := rates.productPrice(*product, currency)
+	if err != nil {
+		return reorderCandidate{}, err
+	}
+	return reorderCandidate{product: product, price: price}, nil
+}
+
+// planReorder decides what to order again for each old line and what changed.
+// The returned items carry only product and quantity; pricing happens when they are ordered.
+func planReorder(previous []models.OrderItem, current map[string]reorderCandidate) ([]models.OrderItem, []models.ReorderChange) {
+	items := []models.OrderItem{}
+	changes := []models.ReorderChange{}
+
+	for _, line := range previous {
+		candidate := current[line.ProductID]
+		change := models.ReorderChange{
+			ProductID:         line.ProductID,
+			Name:              line.Name,
+			RequestedQuantity: line.Quantity,
+		}
+
+		if candidate.product == nil {
+			change.Change = models.ReorderChangeUnavailable
+			changes = append(changes, change)
+			continue
+		}
+		if candidate.product.Stock <= 0 {
+			change.Change = models.ReorderChangeOutOfStock
+			changes = append(changes, change)
+			continue
+		}
+
+		quantity := line.Quantity
+		if candidate.product.Stock < quantity {
+			quantity = candidate.product.Stock
+			change.Change = models.ReorderChangeQuantityReduced
+			change.Quantity = quantity
+			changes = append(changes, change)
+		}
+
+		if candidate.price != line.UnitPrice {
+			oldPrice, newPrice := line.UnitPrice, candidate.price
+			change.Change = models.ReorderChangePriceChanged
+			change.Quantity = quantity
+			change.OldPrice, change.NewPrice = &oldPrice, &newPrice
+			changes = append(changes, change)
+		}
+
+		items = append(items, models.OrderItem{ProductID: line.ProductID, Quantity: quantity})
+	}
+
+	return items, changes
+}
+
+// addToCart adds items to the user's cart, creating the cart if they have none.
+func (s *reorderServiceImpl) addToCart(userID string, items []models.OrderItem) (models.Cart, error) {
+	cart, err := s.cartRepo.FindCartByUserID(userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return models.Cart{}, err
+	}
+	exists := err == nil
+
+	for _, item := range items {
+		productID, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			return models.Cart{}, errors.New("invalid product ID")
+		}
+
+		merged := false
+		for i := range cart.Items {
+			if cart.Items[i].ProductID == productID {
+				cart.Items[i].Quantity += item.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			cart.Items = append(cart.Items, models.CartItem{ProductID: productID, Quantity: item.Quantity})
+		}
+	}
+
+	if !exists {
+		cart.UserID = userID
+		return s.cartRepo.CreateCart(cart)
+	}
+
+	return s.cartRepo.UpdateCart(cart.ID.Hex(), cart)
+}
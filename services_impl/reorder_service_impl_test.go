@@ -0,0 +1,38 @@
+package services_impl
+
+import (
+	"testing"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanReorderDropsAndFlagsChanges(t *testing.T) {
+	previous := []models.OrderItem{
+		{ProductID: "soap", Name: "Soap", Quantity: 3, UnitPrice: ngn(50000)},
+		{ProductID: "bucket", Name: "Bucket", Quantity: 2, UnitPrice: ngn(200000)},
+		{ProductID: "mop", Name: "Mop", Quantity: 1, UnitPrice: ngn(300000)},
+		{ProductID: "broom", Name: "Broom", Quantity: 1, UnitPrice: ngn(150000)},
+	}
+	current := map[string]reorderCandidate{
+		"soap":   {product: &models.Product{Stock: 10}, price: ngn(50000)},
+		"bucket": {product: &models.Product{Stock: 1}, price: ngn(250000)},
+		"mop":    {product: &models.Product{Stock: 0}, price: ngn(300000)},
+	}
+
+	items, changes := planReorder(previous, current)
+
+	assert.Equal(t, []models.OrderItem{
+		{ProductID: "soap", Quantity: 3},
+		{ProductID: "bucket", Quantity: 1},
+	}, items)
+
+	oldPrice, newPrice := ngn(200000), ngn(250000)
+	assert.Equal(t, []models.ReorderChange{
+		{ProductID: "bucket", Name: "Bucket", Change: models.ReorderChangeQuantityReduced, RequestedQuantity: 2, Quantity: 1},
+		{ProductID: "bucket", Name: "Bucket", Change: models.ReorderChangePriceChanged, RequestedQuantity: 2, Quantity: 1, OldPrice: &oldPrice, NewPrice: &newPrice},
+		{ProductID: "mop", Name: "Mop", Change: models.ReorderChangeOutOfStock, RequestedQuantity: 1},
+		{ProductID: "broom", Name: "Broom", Change: models.ReorderChangeUnavailable, RequestedQuantity: 1},
+	}, changes)
+}
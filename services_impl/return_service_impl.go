@@ -0,0 +1,377 @@
+package services_impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+	"adhomes-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type returnServiceImpl struct {
+	returnRepo  *repositories.ReturnRepository
+	orderRepo   *repositories.OrderRepository
+	productRepo *repositories.ProductRepository
+	paymentRepo *repositories.PaymentRepository
+	refunder    paymentRefunder
+	window      time.Duration
+}
+
+func NewReturnService(
+	returnRepo *repositories.ReturnRepository,
+	orderRepo *repositories.OrderRepository,
+	productRepo *repositories.ProductRepository,
+	paymentRepo *repositories.PaymentRepository,
+	walletRepo *repositories.WalletRepository,
+	provider services.PaymentProvider,
+	window time.Duration,
+) *returnServiceImpl {
+	return &returnServiceImpl{
+		returnRepo:  returnRepo,
+		orderRepo:   orderRepo,
+		productRepo: productRepo,
+		paymentRepo: paymentRepo,
+		refunder:    newPaymentRefunder(paymentRepo, orderRepo, walletRepo, provider),
+		window:      window,
+	}
+}
+
+// -----------------------------
+// OPEN RETURN (CUSTOMER)
+// -----------------------------
+func (s *returnServiceImpl) CreateReturn(ret models.Return) (models.Return, error) {
+	ret.Reason = strings.TrimSpace(ret.Reason)
+	if ret.Reason == "" {
+		return models.Return{}, errors.New("a reason is required")
+	}
+
+	switch ret.RefundMethod {
+	case "":
+		ret.RefundMethod = models.RefundMethodOriginal
+	case models.RefundMethodWallet, models.RefundMethodOriginal:
+	default:
+		return models.Return{}, errors.New("invalid refund method")
+	}
+
+	order, err := s.orderRepo.FindUserOrder(ret.OrderID, ret.UserID)
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	// Wallets only hold the default currency
+	if ret.RefundMethod == models.RefundMethodWallet && order.TotalAmount.Currency != models.DefaultCurrency {
+		return models.Return{}, errors.New("orders paid in another currency cannot be refunded to the wallet")
+	}
+
+	delivered, ok := deliveredAt(order)
+	if !ok {
+		return models.Return{}, errors.New("only delivered orders can be returned")
+	}
+	if s.window > 0 && time.Since(delivered) > s.window {
+		return models.Return{}, errors.New("return window has passed")
+	}
+
+	ctx := context.Background()
+
+	payments, err := s.paymentRepo.FindByOrderID(ctx, ret.OrderID)
+	if err != nil {
+		return models.Return{}, err
+	}
+	if settledCharge(payments) == nil {
+		return models.Return{}, errors.New("order has no payment to refund")
+	}
+
+	previous, err := s.returnRepo.FindActiveByOrderID(ctx, ret.OrderID)
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	ret.Items, ret.RefundAmount, err = returnItems(order, ret.Items, previous)
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	now := time.Now()
+	ret.ID = primitive.NewObjectID()
+	ret.Status = models.ReturnStatusRequested
+	ret.RefundIDs = nil
+	ret.AdminNote, ret.ReviewedBy, ret.ReviewedAt, ret.ReceivedAt = "", "", nil, nil
+	ret.CreatedAt = now
+	ret.UpdatedAt = now
+
+	return s.returnRepo.Create(ctx, ret)
+}
+
+func (s *returnServiceImpl) GetUserReturns(userID string) ([]models.Return, error) {
+	return s.returnRepo.FindByUserID(userID)
+}
+
+func (s *returnServiceImpl) GetUserReturn(id string, userID string) (models.Return, error) {
+	return s.returnRepo.FindUserReturn(id, userID)
+}
+
+// -----------------------------
+// ADMIN REVIEW
+// -----------------------------
+func (s *returnServiceImpl) GetReturns(status string) ([]models.Return, error) {
+	return s.returnRepo.FindAll(strings.ToLower(strings.TrimSpace(status)))
+}
+
+func (s *returnServiceImpl) GetReturn(id string) (models.Return, error) {
+	return s.returnRepo.FindByID(id)
+}
+
+func (s *returnServiceImpl) ApproveReturn(id string, actor string, note string) (models.Return, error) {
+	return s.review(id, models.ReturnStatusApproved, actor, strings.TrimSpace(note))
+}
+
+func (s *returnServiceImpl) RejectReturn(id string, actor string, reason string) (models.Return, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return models.Return{}, errors.New("a reason is required to reject a return")
+	}
+	return s.review(id, models.ReturnStatusRejected, actor, reason)
+}
+
+func (s *returnServiceImpl) review(id string, status string, actor string, note string) (models.Return, error) {
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return models.Return{}, err
+	}
+	if ret.Status != models.ReturnStatusRequested {
+		return models.Return{}, errors.New("return has already been processed")
+	}
+
+	now := time.Now()
+	err = s.returnRepo.Transition(context.Background(), ret.ID, models.ReturnStatusRequested, bson.M{
+		"status":      status,
+		"admin_note":  note,
+		"reviewed_by": actor,
+		"reviewed_at": now,
+		"updated_at":  now,
+	})
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	return s.returnRepo.FindByID(id)
+}
+
+// -----------------------------
+// RECEIVE RETURN (ADMIN)
+// -----------------------------
+
+// ReceiveReturn books the returned goods back into stock and refunds the customer,
+// spread over the order's charges when it was paid in parts. Wallet refunds are
+// credited together with the restock; card refunds go to the provider once it has
+// committed.
+func (s *returnServiceImpl) ReceiveReturn(id string, actor string) (models.Return, error) {
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return models.Return{}, err
+	}
+	if ret.Status != models.ReturnStatusApproved {
+		return models.Return{}, errors.New("only approved returns can be received")
+	}
+
+	ctx := context.Background()
+	reason := "return " + ret.ID.Hex() + " received by " + actor
+	toWallet := ret.RefundMethod == models.RefundMethodWallet
+
+	var refunds []reservedRefund
+	err = repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		payments, err := s.paymentRepo.FindByOrderID(sc, ret.OrderID)
+		if err != nil {
+			return err
+		}
+		if settledCharge(payments) == nil {
+			return errors.New("order has no payment to refund")
+		}
+		if refundableAmount(payments).Cmp(ret.RefundAmount) < 0 {
+			return errors.New("order has less left to refund than the return is worth")
+		}
+
+		// Never refund more than is left of each charge
+		refunds = nil
+		left := ret.RefundAmount
+		refunded := models.NewMoney(0, ret.RefundAmount.Currency)
+		refundIDs := []string{}
+		for _, charge := range payments {
+			if charge.Type == models.PaymentTypeRefund || charge.Status != "success" || left.IsZero() {
+				continue
+			}
+
+			amount := left.Min(charge.Amount.Sub(refundedAmount(payments, charge.ID.Hex())))
+			if amount.IsZero() || amount.IsNegative() {
+				continue
+			}
+
+			refund, locked, err := s.refunder.reserve(sc, charge, &amount, reason, toWallet)
+			if err != nil {
+				return err
+			}
+			refunds = append(refunds, reservedRefund{charge: locked, refund: refund})
+			refundIDs = append(refundIDs, refund.ID.Hex())
+			left = left.Sub(amount)
+			refunded = refunded.Add(amount)
+		}
+
+		now := time.Now()
+		err = s.returnRepo.Transition(sc, ret.ID, models.ReturnStatusApproved, bson.M{
+			"status":        models.ReturnStatusReceived,
+			"refund_amount": refunded,
+			"refund_ids":    refundIDs,
+			"received_at":   now,
+			"updated_at":    now,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range ret.Items {
+			productID, err := primitive.ObjectIDFromHex(item.ProductID)
+			if err != nil {
+				return errors.New("invalid product ID")
+			}
+			if err := s.productRepo.IncrementStock(sc, productID, item.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Return{}, err
+	}
+
+	for _, r := range refunds {
+		if err := s.refunder.send(ctx, r.charge, r.refund, reason); err != nil {
+			return models.Return{}, fmt.Errorf("return was received but refunding payment %s failed: %w", r.charge.Reference, err)
+		}
+	}
+
+	return s.returnRepo.FindByID(id)
+}
+
+// -----------------------------
+// HELPERS
+// -----------------------------
+
+// deliveredAt reports when the order was delivered, if it has been.
+func deliveredAt(order models.Order) (time.Time, bool) {
+	if utils.NormalizeStatus(order.Status) != utils.OrderStatusDelivered {
+		return time.Time{}, false
+	}
+	for i := len(order.StatusHistory) - 1; i >= 0; i-- {
+		if order.StatusHistory[i].To == utils.OrderStatusDelivered {
+			return order.StatusHistory[i].ChangedAt, true
+		}
+	}
+	// Orders delivered before status history was kept
+	return order.UpdatedAt, true
+}
+
+// refundableAmount is what is left to refund across the order's settled charges.
+func refundableAmount(payments []models.Payment) models.Money {
+	var left models.Money
+	for _, charge := range payments {
+		if charge.Type == models.PaymentTypeRefund || charge.Status != "success" {
+			continue
+		}
+		left = left.Add(charge.Amount.Sub(refundedAmount(payments, charge.ID.Hex())))
+	}
+	return left
+}
+
+// returnItems checks the requested lines against the order and the returns already
+// open on it, and prices each one. It returns the lines and their refund total.
+func returnItems(order models.Order, requested []models.ReturnItem, previous []models.Return) ([]models.ReturnItem, models.Money, error) {
+	if len(requested) == 0 {
+		return nil, models.Money{}, errors.New("a return must contain at least one item")
+	}
+
+	returned := map[string]int{}
+	for _, ret := range previous {
+		for _, item := range ret.Items {
+			returned[item.ProductID] += item.Quantity
+		}
+	}
+
+	// Merge repeated product IDs so the quantity check sees the full amount
+	quantities := map[string]int{}
+	var productIDs []string
+	for _, item := range requested {
+		if item.Quantity <= 0 {
+			return nil, models.Money{}, errors.New("item quantity must be greater than zero")
+		}
+		if _, seen := quantities[item.ProductID]; !seen {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	items := make([]models.ReturnItem, 0, len(quantities))
+	total := models.NewMoney(0, order.TotalAmount.Currency)
+	for _, productID := range productIDs {
+		line, ok := findOrderLine(order, productID)
+		if !ok {
+			return nil, models.Money{}, errors.New("item is not part of this order")
+		}
+
+		quantity := quantities[productID]
+		if returned[productID]+quantity > line.Quantity {
+			return nil, models.Money{}, errors.New("cannot return more items than were ordered")
+		}
+
+		refund := lineRefund(order, line, quantity)
+		items = append(items, models.ReturnItem{
+			ProductID:    productID,
+			Name:         line.Name,
+			Quantity:     quantity,
+			RefundAmount: refund,
+		})
+		total = total.Add(refund)
+	}
+
+	return items, total, nil
+}
+
+func findOrderLine(order models.Order, productID string) (models.OrderItem, bool) {
+	for _, line := range order.Items {
+		if line.ProductID == productID {
+			return line, true
+		}
+	}
+	return models.OrderItem{}, false
+}
+
+// lineRefund is what quantity units of line cost the customer: the line subtotal plus
+// any tax charged on top, less the line's share of the order discount. Delivery is not refunded.
+func lineRefund(order models.Order, line models.OrderItem, quantity int) models.Money {
+	paid := line.Subtotal
+	if !line.TaxInclusive {
+		paid = paid.Add(line.TaxAmount)
+	}
+	paid = paid.Sub(order.Discount.Share(line.Subtotal.Amount, order.Subtotal.Amount))
+
+	return paid.Share(int64(quantity), int64(line.Quantity))
+}
+
+// refundedAmount totals the refunds issued against a charge, including ones still pending.
+func refundedAmount(payments []models.Payment, chargeID string) models.Money {
+	var total models.Money
+	for _, p := range payments {
+		if p.Type == models.PaymentTypeRefund && p.RefundOf == chargeID && p.Status != "failed" {
+			total = total.Add(p.Amount)
+		}
+	}
+	return total
+}
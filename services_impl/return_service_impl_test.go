@@ -0,0 +1,282 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// returnableOrder has a taxed chair line, a tax-inclusive lamp line and a N1,000 discount.
+func returnableOrder() models.Order {
+	return models.Order{
+		Items: []models.OrderItem{
+			{ProductID: "chair", Name: "Chair", Quantity: 2, Subtotal: ngn(1000000), TaxAmount: ngn(75000)},
+			{ProductID: "lamp", Name: "Lamp", Quantity: 1, Subtotal: ngn(500000), TaxAmount: ngn(34884), TaxInclusive: true},
+		},
+		Subtotal:    ngn(1500000),
+		Discount:    ngn(150000),
+		TotalAmount: ngn(1425000),
+	}
+}
+
+func TestLineRefundSharesDiscountAndTax(t *testing.T) {
+	order := returnableOrder()
+
+	// (10,000 + 750 - 1,000) / 2
+	assert.Equal(t, ngn(487500), lineRefund(order, order.Items[0], 1))
+	// 5,000 - 500, the tax is already in the price
+	assert.Equal(t, ngn(450000), lineRefund(order, order.Items[1], 1))
+}
+
+func TestReturnItemsChecksQuantities(t *testing.T) {
+	order := returnableOrder()
+
+	items, total, err := returnItems(order, []models.ReturnItem{
+		{ProductID: "chair", Quantity: 1},
+		{ProductID: "chair", Quantity: 1},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ReturnItem{{ProductID: "chair", Name: "Chair", Quantity: 2, RefundAmount: ngn(975000)}}, items)
+	assert.Equal(t, ngn(975000), total)
+
+	previous := []models.Return{{Items: []models.ReturnItem{{ProductID: "chair", Quantity: 2}}}}
+	_, _, err = returnItems(order, []models.ReturnItem{{ProductID: "chair", Quantity: 1}}, previous)
+	assert.EqualError(t, err, "cannot return more items than were ordered")
+
+	_, _, err = returnItems(order, []models.ReturnItem{{ProductID: "sofa", Quantity: 1}}, nil)
+	assert.EqualError(t, err, "item is not part of this order")
+
+	_, _, err = returnItems(order, []models.ReturnItem{{ProductID: "lamp", Quantity: 0}}, nil)
+	assert.EqualError(t, err, "item quantity must be greater than zero")
+
+	_, _, err = returnItems(order, nil, nil)
+	assert.EqualError(t, err, "a return must contain at least one item")
+}
+
+func TestDeliveredAtUsesStatusHistory(t *testing.T) {
+	delivered := time.Date(2025, 5, 2, 15, 0, 0, 0, time.UTC)
+	order := models.Order{
+		Status: "delivered",
+		StatusHistory: []models.OrderStatusChange{
+			{To: "out for delivery", ChangedAt: delivered.Add(-time.Hour)},
+			{To: "delivered", ChangedAt: delivered},
+		},
+	}
+
+	at, ok := deliveredAt(order)
+	assert.True(t, ok)
+	assert.Equal(t, delivered, at)
+
+	order.Status = "out for delivery"
+	_, ok = deliveredAt(order)
+	assert.False(t, ok)
+}
+
+func TestRefundedAmountSkipsFailedRefunds(t *testing.T) {
+	payments := []models.Payment{
+		{Type: models.PaymentTypeCharge, Amount: ngn(1000000), Status: "success"},
+		{Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(200000), Status: "success"},
+		{Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(100000), Status: "pending"},
+		{Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(50000), Status: "failed"},
+		{Type: models.PaymentTypeRefund, RefundOf: "c2", Amount: ngn(70000), Status: "success"},
+	}
+
+	assert.Equal(t, ngn(300000), refundedAmount(payments, "c1"))
+}
+
+func newMockReturnService(mt *mtest.T, provider *stubProvider) *returnServiceImpl {
+	return NewReturnService(
+		repositories.NewReturnRepository(mt.DB.Collection("returns")),
+		repositories.NewOrderRepository(mt.DB.Collection("orders")),
+		repositories.NewProductRepository(mt.DB.Collection("products")),
+		repositories.NewPaymentRepository(mt.DB.Collection("payments")),
+		repositories.NewWalletRepository(),
+		provider,
+		0,
+	)
+}
+
+func TestCreateReturnRejectsWalletRefundsInOtherCurrencies(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		s := newMockReturnService(mt, &stubProvider{})
+		order := models.Order{
+			ID:          primitive.NewObjectID(),
+			UserID:      "ada@example.com",
+			TotalAmount: models.NewMoney(5000, "USD"),
+		}
+
+		mt.AddMockResponses(findReply(mockDoc(t, order)))
+
+		_, err := s.CreateReturn(models.Return{
+			OrderID:      order.ID.Hex(),
+			UserID:       order.UserID,
+			Reason:       "damaged",
+			RefundMethod: models.RefundMethodWallet,
+		})
+		assert.EqualError(t, err, "orders paid in another currency cannot be refunded to the wallet")
+	})
+}
+
+func TestReceiveReturnRefundsEveryPartOfASplitPayment(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		provider := &stubProvider{}
+		s := newMockReturnService(mt, provider)
+
+		orderID := primitive.NewObjectID().Hex()
+		wallet := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, UserID: "ada@example.com", Type: models.PaymentTypeCharge, Amount: ngn(400000), Method: "wallet", Status: "success", Reference: "wallet-leg"}
+		card := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, UserID: "ada@example.com", Type: models.PaymentTypeCharge, Amount: ngn(600000), Method: "paystack", Status: "success", Reference: "card-leg"}
+		walletRefund := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, Type: models.PaymentTypeRefund, RefundOf: wallet.ID.Hex(), Amount: ngn(400000), Method: "wallet", Status: "success"}
+		refundedWallet := wallet
+		refundedWallet.Status = "refunded"
+
+		productID := primitive.NewObjectID()
+		ret := models.Return{
+			ID:           primitive.NewObjectID(),
+			OrderID:      orderID,
+			UserID:       "ada@example.com",
+			Items:        []models.ReturnItem{{ProductID: productID.Hex(), Quantity: 1}},
+			RefundMethod: models.RefundMethodOriginal,
+			RefundAmount: ngn(800000),
+			Status:       models.ReturnStatusApproved,
+		}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, ret)),
+			findReply(mockDoc(t, wallet), mockDoc(t, card)),
+			// wallet leg: refunded in full to the wallet
+			updateReply(1),
+			findReply(mockDoc(t, wallet), mockDoc(t, card)),
+			findAndModifyReply(mockDoc(t, models.Wallet{UserID: "ada@example.com", Balance: ngn(400000)})),
+			insertReply(),
+			updateReply(1), updateReply(1),
+			// card leg: the rest goes back to the card
+			updateReply(1),
+			findReply(mockDoc(t, refundedWallet), mockDoc(t, card), mockDoc(t, walletRefund)),
+			insertReply(),
+			updateReply(1),
+			// return and stock
+			updateReply(1),
+			updateReply(1),
+			okReply(),
+			findReply(mockDoc(t, ret)),
+		)
+
+		_, err := s.ReceiveReturn(ret.ID.Hex(), "admin")
+		require.NoError(t, err)
+
+		require.Len(t, provider.refunds, 1)
+		assert.Equal(t, "card-leg", provider.refunds[0].Reference)
+		assert.Equal(t, ngn(400000), provider.refunds[0].Amount)
+
+		// The lock comes before the refund is worked out, for each charge
+		filter, update := sentUpdate(t, mt, 0)
+		assert.Equal(t, wallet.ID, filter.Lookup("_id").ObjectID())
+		_, err = update.LookupErr("$set", "locked_at")
+		assert.NoError(t, err)
+		filter, _ = sentUpdate(t, mt, 3)
+		assert.Equal(t, card.ID, filter.Lookup("_id").ObjectID())
+
+		_, update = sentUpdate(t, mt, 4)
+		assert.Equal(t, "partially_refunded", update.Lookup("$set", "payment_status").StringValue())
+
+		_, update = sentUpdate(t, mt, 5)
+		assert.Equal(t, models.ReturnStatusReceived, update.Lookup("$set", "status").StringValue())
+		assert.EqualValues(t, 800000, update.Lookup("$set", "refund_amount", "amount").AsInt64())
+		refundIDs, err := update.Lookup("$set", "refund_ids").Array().Values()
+		require.NoError(t, err)
+		assert.Len(t, refundIDs, 2)
+
+		cardRefund := sentCommand(t, mt, "insert", 1).Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "pending", cardRefund.Lookup("status").StringValue())
+		assert.Equal(t, card.ID.Hex(), cardRefund.Lookup("refund_of").StringValue())
+	})
+}
+
+func TestReceiveReturnOpensAWalletForCardOnlyCustomers(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		provider := &stubProvider{}
+		s := newMockReturnService(mt, provider)
+
+		orderID := primitive.NewObjectID().Hex()
+		card := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, UserID: "ada@example.com", Type: models.PaymentTypeCharge, Amount: ngn(600000), Method: "paystack", Status: "success", Reference: "card-charge"}
+		ret := models.Return{
+			ID:           primitive.NewObjectID(),
+			OrderID:      orderID,
+			UserID:       "ada@example.com",
+			Items:        []models.ReturnItem{{ProductID: primitive.NewObjectID().Hex(), Quantity: 1}},
+			RefundMethod: models.RefundMethodWallet,
+			RefundAmount: ngn(600000),
+			Status:       models.ReturnStatusApproved,
+		}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, ret)),
+			findReply(mockDoc(t, card)),
+			updateReply(1),
+			findReply(mockDoc(t, card)),
+			findAndModifyReply(mockDoc(t, models.Wallet{UserID: "ada@example.com", Balance: ngn(0)})),
+			findAndModifyReply(mockDoc(t, models.Wallet{UserID: "ada@example.com", Balance: ngn(600000)})),
+			insertReply(),
+			updateReply(1), updateReply(1),
+			// return and stock
+			updateReply(1),
+			updateReply(1),
+			okReply(),
+			findReply(mockDoc(t, ret)),
+		)
+
+		_, err := s.ReceiveReturn(ret.ID.Hex(), "admin")
+		require.NoError(t, err)
+		assert.Empty(t, provider.refunds)
+
+		// The wallet is opened before it is credited
+		ensure := sentCommand(t, mt, "findAndModify", 0).Command
+		assert.True(t, ensure.Lookup("upsert").Boolean())
+		assert.Equal(t, "NGN", ensure.Lookup("update", "$setOnInsert", "balance", "currency").StringValue())
+		credit := sentCommand(t, mt, "findAndModify", 1).Command
+		assert.EqualValues(t, 600000, credit.Lookup("update", "$inc", "balance.amount").AsInt64())
+
+		refund := sentCommand(t, mt, "insert", 0).Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "wallet", refund.Lookup("method").StringValue())
+		assert.Equal(t, "success", refund.Lookup("status").StringValue())
+	})
+}
+
+func TestReceiveReturnRefusesMoreThanIsLeftToRefund(t *testing.T) {
+	withMockDB(t, func(mt *mtest.T) {
+		provider := &stubProvider{}
+		s := newMockReturnService(mt, provider)
+
+		orderID := primitive.NewObjectID().Hex()
+		card := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, UserID: "ada@example.com", Type: models.PaymentTypeCharge, Amount: ngn(600000), Method: "paystack", Status: "success", Reference: "card-charge"}
+		earlier := models.Payment{ID: primitive.NewObjectID(), OrderID: orderID, Type: models.PaymentTypeRefund, RefundOf: card.ID.Hex(), Amount: ngn(300000), Method: "paystack", Status: "success"}
+		ret := models.Return{
+			ID:           primitive.NewObjectID(),
+			OrderID:      orderID,
+			UserID:       "ada@example.com",
+			Items:        []models.ReturnItem{{ProductID: primitive.NewObjectID().Hex(), Quantity: 1}},
+			RefundMethod: models.RefundMethodOriginal,
+			RefundAmount: ngn(500000),
+			Status:       models.ReturnStatusApproved,
+		}
+
+		mt.AddMockResponses(
+			findReply(mockDoc(t, ret)),
+			findReply(mockDoc(t, card), mockDoc(t, earlier)),
+			okReply(), // abortTransaction
+		)
+
+		_, err := s.ReceiveReturn(ret.ID.Hex(), "admin")
+		assert.EqualError(t, err, "order has less left to refund than the return is worth")
+		assert.Empty(t, provider.refunds)
+		assert.NotContains(t, sentCommands(mt), "update")
+		assert.NotContains(t, sentCommands(mt), "insert")
+	})
+}
@@ -0,0 +1,140 @@
+package services_impl
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type taxServiceImpl struct {
+	taxRepo   *repositories.TaxRateRepository
+	orderRepo *repositories.OrderRepository
+}
+
+func NewTaxService(taxRepo *repositories.TaxRateRepository, orderRepo *repositories.OrderRepository) *taxServiceImpl {
+	return &taxServiceImpl{
+		taxRepo:   taxRepo,
+		orderRepo: orderRepo,
+	}
+}
+
+// -----------------------------
+// ADMIN CRUD
+// -----------------------------
+func (s *taxServiceImpl) CreateTaxRate(rate models.TaxRate) (models.TaxRate, error) {
+	rate.ID = primitive.NewObjectID()
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = time.Now()
+	return s.taxRepo.Create(rate)
+}
+
+func (s *taxServiceImpl) GetTaxRates() ([]models.TaxRate, error) {
+	return s.taxRepo.FindAll()
+}
+
+func (s *taxServiceImpl) UpdateTaxRate(id string, rate models.TaxRate) (models.TaxRate, error) {
+	rate.UpdatedAt = time.Now()
+	return s.taxRepo.Update(id, rate)
+}
+
+func (s *taxServiceImpl) DeleteTaxRate(id string) error {
+	return s.taxRepo.Delete(id)
+}
+
+// -----------------------------
+// REPORT
+// -----------------------------
+func (s *taxServiceImpl) TaxReport(from, to string) ([]models.TaxReportRow, error) {
+	filter := bson.M{"status": bson.M{"$ne": utils.OrderStatusCancelled}}
+
+	createdAt := bson.M{}
+	if from != "" {
+		t, err := parseDate(from, false)
+		if err != nil {
+			return nil, errors.New("invalid from date")
+		}
+		createdAt["$gte"] = t
+	}
+	if to != "" {
+		t, err := parseDate(to, true)
+		if err != nil {
+			return nil, errors.New("invalid to date")
+		}
+		createdAt["$lte"] = t
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return s.orderRepo.TaxSummary(filter)
+}
+
+// -----------------------------
+// CALCULATION
+// -----------------------------
+
+// applyTax sets the tax fields of every line from the best matching rate and returns
+// the total tax plus the part of it that must be added on top of catalogue prices
+// (tax-exclusive lines). Tax is computed on each line before any coupon discount.
+func applyTax(rates []models.TaxRate, items []models.OrderItem, state string, currency string) (taxTotal models.Money, exclusiveTotal models.Money) {
+	taxTotal = models.NewMoney(0, currency)
+	exclusiveTotal = models.NewMoney(0, currency)
+
+	for i := range items {
+		item := &items[i]
+		item.TaxRate, item.TaxInclusive = 0, false
+		item.TaxAmount, item.NetAmount = models.NewMoney(0, currency), item.Subtotal
+
+		rate := matchTaxRate(rates, item.Category, state)
+		if rate == nil {
+			continue
+		}
+
+		item.TaxRate = rate.Rate
+		item.TaxInclusive = rate.Inclusive
+		if rate.Inclusive {
+			item.TaxAmount = item.Subtotal.IncludedTax(rate.Rate)
+			item.NetAmount = item.Subtotal.Sub(item.TaxAmount)
+		} else {
+			item.TaxAmount = item.Subtotal.Percent(rate.Rate)
+			exclusiveTotal = exclusiveTotal.Add(item.TaxAmount)
+		}
+		taxTotal = taxTotal.Add(item.TaxAmount)
+	}
+	return taxTotal, exclusiveTotal
+}
+
+// matchTaxRate prefers a rate naming both category and state, then category, then state,
+// then a catch-all rate.
+func matchTaxRate(rates []models.TaxRate, category, state string) *models.TaxRate {
+	var best *models.TaxRate
+	bestScore := -1
+
+	for i, rate := range rates {
+		score := 0
+		if rate.Category != "" {
+			if !strings.EqualFold(rate.Category, category) {
+				continue
+			}
+			score += 2
+		}
+		if rate.State != "" {
+			if !strings.EqualFold(rate.State, strings.TrimSpace(state)) {
+				continue
+			}
+			score += 1
+		}
+
+		if score > bestScore {
+			best, bestScore = &rates[i], score
+		}
+	}
+	return best
+}
@@ -0,0 +1,40 @@
+package services_impl
+
+import (
+	"testing"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTaxHandlesInclusiveAndExclusiveRates(t *testing.T) {
+	rates := []models.TaxRate{
+		{Name: "VAT", Rate: 7.5},
+		{Name: "Lagos food", Category: "food", State: "Lagos", Rate: 5, Inclusive: true},
+	}
+	items := []models.OrderItem{
+		{Category: "kitchen", Subtotal: ngn(100000)},
+		{Category: "food", Subtotal: ngn(105000)},
+	}
+
+	taxTotal, exclusive := applyTax(rates, items, "lagos", "NGN")
+
+	assert.Equal(t, ngn(7500), items[0].TaxAmount)
+	assert.Equal(t, ngn(100000), items[0].NetAmount)
+	assert.Equal(t, ngn(5000), items[1].TaxAmount)
+	assert.Equal(t, ngn(100000), items[1].NetAmount)
+	assert.Equal(t, ngn(12500), taxTotal)
+	assert.Equal(t, ngn(7500), exclusive)
+}
+
+func TestApplyTaxRoundsEachLine(t *testing.T) {
+	rates := []models.TaxRate{{Name: "VAT", Rate: 7.5}}
+	items := []models.OrderItem{{Subtotal: ngn(999)}, {Subtotal: ngn(999)}}
+
+	taxTotal, _ := applyTax(rates, items, "", "NGN")
+
+	// 74.925 kobo per line rounds to 75, so the order carries 150 rather than 149.85
+	assert.Equal(t, ngn(75), items[0].TaxAmount)
+	assert.Equal(t, ngn(150), taxTotal)
+}
@@ -1,7 +1,9 @@
 package services_impl
 
 import (
+	"context"
 	"errors"
+	"log"
 	"time"
 
 	"adhomes-backend/models"
@@ -14,12 +16,14 @@ import (
 )
 
 type userServiceImpl struct {
-	userRepo *repositories.UserRepository
+	userRepo   *repositories.UserRepository
+	walletRepo *repositories.WalletRepository
 }
 
-func NewUserService(userRepo *repositories.UserRepository) *userServiceImpl {
+func NewUserService(userRepo *repositories.UserRepository, walletRepo *repositories.WalletRepository) *userServiceImpl {
 	return &userServiceImpl{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
 	}
 }
 
@@ -47,7 +51,16 @@ func (s *userServiceImpl) Register(user models.User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 
-	return s.userRepo.CreateUser(user)
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return err
+	}
+
+	// Wallets are keyed by the email the token carries. A wallet that fails to open
+	// here is created on first access instead, so signup does not fail over it.
+	if _, err := s.walletRepo.EnsureWallet(context.Background(), user.Email, models.DefaultCurrency); err != nil {
+		log.Printf("failed to open wallet for %s: %v", user.Email, err)
+	}
+	return nil
 }
 
 func (s *userServiceImpl) Login(email, password string) (string, error) {
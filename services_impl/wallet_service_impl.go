@@ -14,9 +14,9 @@ type WalletServiceImpl struct {
 }
 
 // Constructor
-func NewWalletService() services.WalletService {
+func NewWalletService(walletRepo *repositories.WalletRepository) services.WalletService {
 	return &WalletServiceImpl{
-		walletRepo: repositories.NewWalletRepository(),
+		walletRepo: walletRepo,
 	}
 }
 
@@ -27,7 +27,7 @@ func (w *WalletServiceImpl) GetWalletByUserID(
 	ctx context.Context,
 	userID string,
 ) (*models.Wallet, error) {
-	return w.walletRepo.FindByUserID(ctx, userID)
+	return w.walletRepo.EnsureWallet(ctx, userID, models.DefaultCurrency)
 }
 
 // -----------------------------
@@ -36,10 +36,10 @@ func (w *WalletServiceImpl) GetWalletByUserID(
 func (w *WalletServiceImpl) IncreaseBalance(
 	ctx context.Context,
 	userID string,
-	amount float64,
+	amount models.Money,
 ) (*models.Wallet, error) {
 
-	if amount <= 0 {
+	if amount.Amount <= 0 {
 		return nil, errors.New("amount must be greater than zero")
 	}
 
@@ -52,10 +52,10 @@ func (w *WalletServiceImpl) IncreaseBalance(
 func (w *WalletServiceImpl) DecreaseBalance(
 	ctx context.Context,
 	userID string,
-	amount float64,
+	amount models.Money,
 ) (*models.Wallet, error) {
 
-	if amount <= 0 {
+	if amount.Amount <= 0 {
 		return nil, errors.New("amount must be greater than zero")
 	}
 
@@ -64,7 +64,7 @@ func (w *WalletServiceImpl) DecreaseBalance(
 		return nil, err
 	}
 
-	if wallet.Balance < amount {
+	if wallet.Balance.Cmp(amount) < 0 {
 		return nil, errors.New("insufficient balance")
 	}
 
@@ -0,0 +1,290 @@
+package services_impl
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"adhomes-backend/models"
+	"adhomes-backend/repositories"
+	"adhomes-backend/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// webhookEventListLimit caps the admin event listing.
+const webhookEventListLimit = 200
+
+type webhookServiceImpl struct {
+	eventRepo   *repositories.WebhookEventRepository
+	paymentRepo *repositories.PaymentRepository
+	orderRepo   *repositories.OrderRepository
+	provider    services.PaymentProvider
+	settler     paymentSettler
+}
+
+func NewWebhookService(
+	eventRepo *repositories.WebhookEventRepository,
+	paymentRepo *repositories.PaymentRepository,
+	orderRepo *repositories.OrderRepository,
+	walletRepo *repositories.WalletRepository,
+	provider services.PaymentProvider,
+) *webhookServiceImpl {
+	return &webhookServiceImpl{
+		eventRepo:   eventRepo,
+		paymentRepo: paymentRepo,
+		orderRepo:   orderRepo,
+		provider:    provider,
+		settler:     paymentSettler{paymentRepo: paymentRepo, orderRepo: orderRepo, walletRepo: walletRepo},
+	}
+}
+
+// -----------------------------
+// RECEIVE
+// -----------------------------
+
+// HandlePaystack logs the raw event before acting on it, so every delivery can be
+// replayed. Paystack resends an event until it gets a 2xx, and may send it more than
+// once anyway, so applying an event twice changes nothing.
+func (s *webhookServiceImpl) HandlePaystack(body []byte, signature string) error {
+	if !s.provider.VerifyWebhook(body, signature) {
+		return errors.New("invalid webhook signature")
+	}
+
+	event := models.WebhookEvent{
+		Provider:   paystackProviderName,
+		Payload:    string(body),
+		Status:     models.WebhookStatusReceived,
+		ReceivedAt: time.Now(),
+	}
+
+	parsed, parseErr := s.provider.ParseWebhook(body)
+	if parseErr == nil {
+		event.Event = parsed.Type
+		event.Reference = parsed.Reference
+	}
+
+	event, err := s.eventRepo.Create(event)
+	if err != nil {
+		return err
+	}
+
+	if parseErr != nil {
+		s.recordOutcome(event, models.WebhookStatusFailed, parseErr.Error())
+		return parseErr
+	}
+
+	return s.process(event, parsed)
+}
+
+// -----------------------------
+// ADMIN
+// -----------------------------
+func (s *webhookServiceImpl) GetEvents(status string) ([]models.WebhookEvent, error) {
+	return s.eventRepo.FindAll(status, webhookEventListLimit)
+}
+
+// ReplayEvent applies a logged event again, e.g. after fixing what made it fail.
+func (s *webhookServiceImpl) ReplayEvent(id string) (models.WebhookEvent, error) {
+	event, err := s.eventRepo.FindByID(id)
+	if err != nil {
+		return models.WebhookEvent{}, err
+	}
+
+	parsed, err := s.provider.ParseWebhook([]byte(event.Payload))
+	if err != nil {
+		s.recordOutcome(event, models.WebhookStatusFailed, err.Error())
+		return models.WebhookEvent{}, err
+	}
+
+	if err := s.process(event, parsed); err != nil {
+		return models.WebhookEvent{}, err
+	}
+	return s.eventRepo.FindByID(id)
+}
+
+// -----------------------------
+// PROCESSING
+// -----------------------------
+
+// process applies the event and records the outcome. Only errors worth a retry,
+// such as a database failure, are returned.
+func (s *webhookServiceImpl) process(event models.WebhookEvent, parsed models.PaymentEvent) error {
+	status, note, err := s.apply(parsed)
+	if err != nil {
+		s.recordOutcome(event, models.WebhookStatusFailed, err.Error())
+		return err
+	}
+
+	s.recordOutcome(event, status, note)
+	return nil
+}
+
+func (s *webhookServiceImpl) recordOutcome(event models.WebhookEvent, status string, note string) {
+	if err := s.eventRepo.RecordOutcome(event.ID, status, note, time.Now()); err != nil {
+		log.Printf("failed to record outcome of webhook event %s: %v", event.ID.Hex(), err)
+	}
+}
+
+func (s *webhookServiceImpl) apply(event models.PaymentEvent) (string, string, error) {
+	switch event.Type {
+	case models.PaymentEventChargeSuccess:
+		return s.chargeSucceeded(event)
+	case models.PaymentEventChargeFailed:
+		return s.chargeFailed(event)
+	case models.PaymentEventRefundProcessed:
+		return s.refundSettled(event, "success")
+	case models.PaymentEventRefundFailed:
+		return s.refundSettled(event, "failed")
+	}
+	return models.WebhookStatusIgnored, "event type not handled", nil
+}
+
+// findCharge looks up the charge an event is about. A reference we never issued is
+// reported as a note, not an error: retrying would not make it known.
+func (s *webhookServiceImpl) findCharge(ctx context.Context, reference string) (models.Payment, string, error) {
+	payment, err := s.paymentRepo.FindByReference(ctx, reference)
+	if err != nil {
+		if err.Error() == "payment not found" {
+			return models.Payment{}, "unknown payment reference", nil
+		}
+		return models.Payment{}, "", err
+	}
+	if payment.Type == models.PaymentTypeRefund {
+		return models.Payment{}, "reference belongs to a refund", nil
+	}
+	return payment, "", nil
+}
+
+// chargeSucceeded marks the payment successful and the order paid.
+func (s *webhookServiceImpl) chargeSucceeded(event models.PaymentEvent) (string, string, error) {
+	ctx := context.Background()
+
+	payment, note, err := s.findCharge(ctx, event.Reference)
+	if err != nil || note != "" {
+		return models.WebhookStatusIgnored, note, err
+	}
+
+	if payment.Status == "success" || payment.Status == "refunded" {
+		return models.WebhookStatusProcessed, "already recorded", nil
+	}
+	if event.Amount != payment.Amount {
+		return models.WebhookStatusFailed, "amount " + event.Amount.String() + " does not match payment " + payment.Amount.String(), nil
+	}
+
+	_, note, err = s.settler.settleCharge(ctx, payment)
+	if err != nil {
+		return "", "", err
+	}
+	return models.WebhookStatusProcessed, note, nil
+}
+
+// chargeFailed marks a pending payment failed.
+func (s *webhookServiceImpl) chargeFailed(event models.PaymentEvent) (string, string, error) {
+	ctx := context.Background()
+
+	payment, note, err := s.findCharge(ctx, event.Reference)
+	if err != nil || note != "" {
+		return models.WebhookStatusIgnored, note, err
+	}
+
+	changed, err := s.settler.failCharge(ctx, payment)
+	if err != nil {
+		return "", "", err
+	}
+	if !changed {
+		return models.WebhookStatusProcessed, "payment already " + payment.Status, nil
+	}
+	return models.WebhookStatusProcessed, "", nil
+}
+
+// refundSettled settles the oldest pending refund of the event's charge and amount,
+// then brings the charge and the order's payment status up to date.
+func (s *webhookServiceImpl) refundSettled(event models.PaymentEvent, status string) (string, string, error) {
+	ctx := context.Background()
+
+	charge, note, err := s.findCharge(ctx, event.Reference)
+	if err != nil || note != "" {
+		return models.WebhookStatusIgnored, note, err
+	}
+
+	payments, err := s.paymentRepo.FindByOrderID(ctx, charge.OrderID)
+	if err != nil {
+		return "", "", err
+	}
+
+	refund := pendingRefund(payments, charge.ID.Hex(), event.Amount)
+	if refund == nil {
+		return models.WebhookStatusIgnored, "no pending refund matches this event", nil
+	}
+	refund.Status = status
+
+	err = repositories.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		changed, err := s.paymentRepo.UpdateStatusFrom(sc, refund.ID, []string{"pending"}, status)
+		if err != nil || !changed {
+			return err
+		}
+		return s.settler.applyRefunds(sc, charge, payments)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return models.WebhookStatusProcessed, "", nil
+}
+
+// pendingRefund finds the oldest pending refund of chargeID for amount. A zero
+// amount matches any refund, for events that leave it out.
+func pendingRefund(payments []models.Payment, chargeID string, amount models.Money) *models.Payment {
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+	})
+
+	for i, p := range payments {
+		if p.Type != models.PaymentTypeRefund || p.RefundOf != chargeID || p.Status != "pending" {
+			continue
+		}
+		if amount.IsZero() || p.Amount == amount {
+			return &payments[i]
+		}
+	}
+	return nil
+}
+
+// refundStatuses works out the charge status and the order's payment status from the
+// refunds issued against the charge so far.
+func refundStatuses(charge models.Payment, payments []models.Payment) (string, string) {
+	refunded := refundedAmount(payments, charge.ID.Hex())
+
+	switch {
+	case refunded.Cmp(charge.Amount) >= 0:
+		return "refunded", "refunded"
+	case refunded.IsZero():
+		return "success", "paid"
+	}
+	return "success", "partially_refunded"
+}
+
+// orderRefundStatus works out the order's payment status from the refunds issued
+// against every settled charge among payments, so that refunding one part of a
+// split payment leaves the order partially refunded.
+func orderRefundStatus(payments []models.Payment) string {
+	status := ""
+	for _, p := range payments {
+		if p.Type == models.PaymentTypeRefund || (p.Status != "success" && p.Status != "refunded") {
+			continue
+		}
+
+		_, charged := refundStatuses(p, payments)
+		switch status {
+		case "":
+			status = charged
+		case charged:
+		default:
+			status = "partially_refunded"
+		}
+	}
+	return status
+}
@@ -0,0 +1,58 @@
+package services_impl
+
+import (
+	"testing"
+	"time"
+
+	"adhomes-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPendingRefundPicksOldestMatch(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	payments := []models.Payment{
+		{Reference: "newer", Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(5000), Status: "pending", CreatedAt: start.Add(time.Hour)},
+		{Reference: "settled", Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(5000), Status: "success", CreatedAt: start},
+		{Reference: "older", Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(5000), Status: "pending", CreatedAt: start.Add(time.Minute)},
+		{Reference: "other", Type: models.PaymentTypeRefund, RefundOf: "c1", Amount: ngn(7000), Status: "pending", CreatedAt: start},
+	}
+
+	assert.Equal(t, "older", pendingRefund(payments, "c1", ngn(5000)).Reference)
+	assert.Equal(t, "other", pendingRefund(payments, "c1", models.Money{}).Reference)
+	assert.Nil(t, pendingRefund(payments, "c1", ngn(9000)))
+	assert.Nil(t, pendingRefund(payments, "c2", ngn(5000)))
+}
+
+func TestRefundStatuses(t *testing.T) {
+	charge := models.Payment{ID: primitive.NewObjectID(), Amount: ngn(10000), Status: "success"}
+	refund := func(amount int64, status string) models.Payment {
+		return models.Payment{Type: models.PaymentTypeRefund, RefundOf: charge.ID.Hex(), Amount: ngn(amount), Status: status}
+	}
+
+	chargeStatus, orderStatus := refundStatuses(charge, []models.Payment{charge, refund(4000, "success")})
+	assert.Equal(t, "success", chargeStatus)
+	assert.Equal(t, "partially_refunded", orderStatus)
+
+	chargeStatus, orderStatus = refundStatuses(charge, []models.Payment{charge, refund(4000, "success"), refund(6000, "success")})
+	assert.Equal(t, "refunded", chargeStatus)
+	assert.Equal(t, "refunded", orderStatus)
+
+	chargeStatus, orderStatus = refundStatuses(charge, []models.Payment{charge, refund(10000, "failed")})
+	assert.Equal(t, "success", chargeStatus)
+	assert.Equal(t, "paid", orderStatus)
+}
+
+func TestOrderRefundStatusCoversEveryCharge(t *testing.T) {
+	wallet := models.Payment{ID: primitive.NewObjectID(), Amount: ngn(4000), Method: "wallet", Status: "success"}
+	card := models.Payment{ID: primitive.NewObjectID(), Amount: ngn(6000), Method: "paystack", Status: "success"}
+	refund := func(charge models.Payment) models.Payment {
+		return models.Payment{Type: models.PaymentTypeRefund, RefundOf: charge.ID.Hex(), Amount: charge.Amount, Status: "success"}
+	}
+
+	assert.Equal(t, "paid", orderRefundStatus([]models.Payment{wallet, card}))
+	assert.Equal(t, "partially_refunded", orderRefundStatus([]models.Payment{wallet, card, refund(wallet)}))
+	assert.Equal(t, "refunded", orderRefundStatus([]models.Payment{wallet, card, refund(wallet), refund(card)}))
+	assert.Equal(t, "", orderRefundStatus(nil))
+}
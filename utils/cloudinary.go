@@ -32,6 +32,11 @@ func InitCloudinary() error {
 }
 
 func UploadToCloudinary(file *multipart.FileHeader) (string, string, error) {
+	return UploadToCloudinaryFolder(file, "adhomes/products")
+}
+
+// UploadToCloudinaryFolder uploads file into folder and returns its URL and public ID.
+func UploadToCloudinaryFolder(file *multipart.FileHeader, folder string) (string, string, error) {
 	f, err := file.Open()
 	if err != nil {
 		return "", "", err
@@ -42,7 +47,7 @@ func UploadToCloudinary(file *multipart.FileHeader) (string, string, error) {
 		context.Background(),
 		f,
 		uploader.UploadParams{
-			Folder: "adhomes/products",
+			Folder: folder,
 		},
 	)
 	if err != nil {
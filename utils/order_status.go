@@ -1,17 +1,112 @@
 package utils
 
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	OrderStatusPending        = "pending"
+	OrderStatusPaid           = "paid"
+	OrderStatusApproved       = "approved"
+	OrderStatusProcessing     = "processing"
+	OrderStatusPreparing      = "preparing"
+	OrderStatusOutForDelivery = "out for delivery"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+)
+
+// OrderStatusPaid is no longer set: payment only moves an order's payment_status. It is
+// kept so that orders stored while payment still set it can be read and moved on.
 var AllowedStatuses = []string{
-	"Processing",
-	"Preparing",
-	"Out for Delivery",
-	"Delivered",
+	OrderStatusPending,
+	OrderStatusPaid,
+	OrderStatusApproved,
+	OrderStatusProcessing,
+	OrderStatusPreparing,
+	OrderStatusOutForDelivery,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+}
+
+// FulfilmentStatuses are the statuses staff move an order through once it is being
+// prepared. Only admins set them; customers may only cancel.
+var FulfilmentStatuses = []string{
+	OrderStatusProcessing,
+	OrderStatusPreparing,
+	OrderStatusOutForDelivery,
+	OrderStatusDelivered,
+}
+
+// orderTransitions lists, for every status, the statuses an order may move to next.
+// Delivered and cancelled are terminal. Nothing moves into "paid" any more; legacy
+// orders in it carry on as if approval were still to come.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:        {OrderStatusApproved, OrderStatusCancelled},
+	OrderStatusPaid:           {OrderStatusApproved, OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusApproved:       {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing:     {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing:      {OrderStatusOutForDelivery},
+	OrderStatusOutForDelivery: {OrderStatusDelivered},
+	OrderStatusDelivered:      {},
+	OrderStatusCancelled:      {},
+}
+
+// InvalidTransitionError is returned when an order is asked to move
+// to a status that is not reachable from its current one.
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %q to %q", e.From, e.To)
+}
+
+// NormalizeStatus maps user input and legacy values ("Processing") onto the canonical status.
+func NormalizeStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
 }
 
 func IsValidStatus(status string) bool {
 	for _, s := range AllowedStatuses {
-		if s == status {
+		if s == NormalizeStatus(status) {
+			return true
+		}
+	}
+	return false
+}
+
+func IsFulfilmentStatus(status string) bool {
+	for _, s := range FulfilmentStatuses {
+		if s == NormalizeStatus(status) {
 			return true
 		}
 	}
 	return false
 }
+
+// payableStatuses are the statuses in which an order can still take payment.
+var payableStatuses = []string{OrderStatusPending, OrderStatusApproved}
+
+// IsPayableStatus reports whether an order in status can still be paid for.
+func IsPayableStatus(status string) bool {
+	for _, s := range payableStatuses {
+		if s == NormalizeStatus(status) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition checks that an order in status from may move to status to.
+func ValidateTransition(from, to string) error {
+	from, to = NormalizeStatus(from), NormalizeStatus(to)
+
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &InvalidTransitionError{From: from, To: to}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransitionAllowsLifecycle(t *testing.T) {
+	path := []string{
+		OrderStatusPending,
+		OrderStatusApproved,
+		OrderStatusProcessing,
+		OrderStatusPreparing,
+		OrderStatusOutForDelivery,
+		OrderStatusDelivered,
+	}
+
+	for i := 0; i < len(path)-1; i++ {
+		assert.NoError(t, ValidateTransition(path[i], path[i+1]), "%s -> %s", path[i], path[i+1])
+	}
+}
+
+func TestValidateTransitionRejectsIllegalMoves(t *testing.T) {
+	cases := [][2]string{
+		{OrderStatusDelivered, OrderStatusPending},
+		{OrderStatusCancelled, OrderStatusPaid},
+		{OrderStatusPending, OrderStatusPaid},
+		{OrderStatusApproved, OrderStatusPaid},
+		{OrderStatusPending, OrderStatusDelivered},
+		{OrderStatusOutForDelivery, OrderStatusCancelled},
+	}
+
+	for _, c := range cases {
+		err := ValidateTransition(c[0], c[1])
+
+		var transitionErr *InvalidTransitionError
+		assert.True(t, errors.As(err, &transitionErr), "%s -> %s", c[0], c[1])
+		assert.Equal(t, c[0], transitionErr.From)
+		assert.Equal(t, c[1], transitionErr.To)
+	}
+}
+
+func TestValidateTransitionNormalizesLegacyStatuses(t *testing.T) {
+	assert.NoError(t, ValidateTransition("Processing", "Preparing"))
+	assert.True(t, IsValidStatus("Out for Delivery"))
+}
+
+func TestFulfilmentStatusesExcludeCustomerAndPaymentStatuses(t *testing.T) {
+	for _, status := range []string{OrderStatusProcessing, "Preparing", OrderStatusOutForDelivery, OrderStatusDelivered} {
+		assert.True(t, IsFulfilmentStatus(status), status)
+	}
+	for _, status := range []string{OrderStatusPending, OrderStatusPaid, OrderStatusApproved, OrderStatusCancelled, "shipped"} {
+		assert.False(t, IsFulfilmentStatus(status), status)
+	}
+}
+
+func TestLegacyPaidOrdersCanMoveOn(t *testing.T) {
+	assert.NoError(t, ValidateTransition(OrderStatusPaid, OrderStatusApproved))
+	assert.NoError(t, ValidateTransition(OrderStatusPaid, OrderStatusCancelled))
+}
+
+func TestIsPayableStatus(t *testing.T) {
+	assert.True(t, IsPayableStatus(OrderStatusPending))
+	assert.True(t, IsPayableStatus("Approved"))
+	for _, status := range []string{OrderStatusPaid, OrderStatusProcessing, OrderStatusDelivered, OrderStatusCancelled} {
+		assert.False(t, IsPayableStatus(status), status)
+	}
+}
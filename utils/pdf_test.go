@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDFBytesProducesWellFormedDocument(t *testing.T) {
+	pdf := NewPDF()
+	pdf.Text(50, 800, 12, true, "Invoice (copy) \\ total")
+	pdf.AddPage()
+	pdf.Line(50, 50, 100, 50)
+
+	out := pdf.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), "/Count 2")
+	assert.Contains(t, string(out), `(Invoice \(copy\) \\ total) Tj`)
+}
+
+func TestTextWidthUsesHelveticaMetrics(t *testing.T) {
+	assert.InDelta(t, 5.56, TextWidth("0", 10), 0.001)
+	assert.InDelta(t, 2.78, TextWidth(" ", 10), 0.001)
+}
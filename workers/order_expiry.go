@@ -0,0 +1,20 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"adhomes-backend/services"
+)
+
+// OrderExpiryJob cancels orders that have stayed unpaid for longer than after.
+func OrderExpiryJob(orderService services.OrderService, after time.Duration) Job {
+	return func(ctx context.Context) error {
+		expired, err := orderService.ExpireUnpaidOrders(ctx, after)
+		if expired > 0 {
+			log.Printf("expired %d unpaid orders", expired)
+		}
+		return err
+	}
+}
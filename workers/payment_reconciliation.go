@@ -0,0 +1,21 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"adhomes-backend/services"
+)
+
+// PaymentReconciliationJob re-verifies card payments pending for longer than after and
+// releases split payment wallet holds older than holdTimeout.
+func PaymentReconciliationJob(paymentService services.PaymentService, after time.Duration, holdTimeout time.Duration) Job {
+	return func(ctx context.Context) error {
+		report, err := paymentService.ReconcilePendingPayments(ctx, after, holdTimeout)
+		if len(report.Discrepancies) > 0 {
+			log.Printf("payment reconciliation checked %d payments, found %d discrepancies", report.Checked, len(report.Discrepancies))
+		}
+		return err
+	}
+}
@@ -0,0 +1,118 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"adhomes-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job is a single run of a periodic background task.
+type Job func(ctx context.Context) error
+
+type task struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Scheduler runs periodic jobs inside the API process. Every run first takes a
+// Mongo lease named after the job, so when several instances are deployed only
+// one of them runs a given job at a time.
+type Scheduler struct {
+	leases *repositories.LeaseRepository
+	holder string
+	tasks  []task
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(leases *repositories.LeaseRepository) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		leases: leases,
+		holder: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Every registers job to run every interval once the scheduler is started.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	s.tasks = append(s.tasks, task{name: name, interval: interval, job: job})
+}
+
+// Start launches every registered job in the background.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go func(t task) {
+			defer s.wg.Done()
+			s.loop(ctx, t)
+		}(t)
+	}
+}
+
+// Stop cancels running jobs, waits for them to return and hands their leases back
+// so another instance can take over straight away.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, t := range s.tasks {
+		if err := s.leases.Release(ctx, t.name, s.holder); err != nil {
+			log.Printf("failed to release %s lease: %v", t.name, err)
+		}
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, t task) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, t)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs the job if this instance holds its lease. The lease lasts one
+// interval and the run is cut off at the same point, so a slow run cannot
+// overlap with another instance's.
+func (s *Scheduler) runOnce(ctx context.Context, t task) {
+	acquired, err := s.leases.Acquire(ctx, t.name, s.holder, t.interval)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("failed to acquire %s lease: %v", t.name, err)
+		}
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, t.interval)
+	defer cancel()
+
+	if err := t.job(runCtx); err != nil && ctx.Err() == nil {
+		log.Printf("%s failed: %v", t.name, err)
+	}
+}